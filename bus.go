@@ -0,0 +1,175 @@
+package cachego
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The `LocalBus` type is an in-process `InvalidationBus` that delivers every event synchronously to
+// all subscribers. It stands in for a `RedisBus` in tests, where several caches in the same process
+// play the role of separate instances.
+type LocalBus struct {
+	mu       sync.RWMutex
+	next     int
+	handlers map[int]func(InvalidationEvent)
+}
+
+// The `NewLocalBus` function creates an empty `LocalBus`.
+func NewLocalBus() *LocalBus {
+	return &LocalBus{handlers: map[int]func(InvalidationEvent){}}
+}
+
+// The `Publish` function calls every subscribed handler with the event before returning.
+func (b *LocalBus) Publish(ctx context.Context, event InvalidationEvent) error {
+	b.mu.RLock()
+	handlers := make([]func(InvalidationEvent), 0, len(b.handlers))
+	for _, handler := range b.handlers {
+		handlers = append(handlers, handler)
+	}
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+
+	return nil
+}
+
+// The `Subscribe` function registers a handler and returns a function removing it.
+func (b *LocalBus) Subscribe(handler func(InvalidationEvent)) (func(), error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	id := b.next
+	b.next++
+	b.handlers[id] = handler
+
+	return func() {
+		b.mu.Lock()
+		delete(b.handlers, id)
+		b.mu.Unlock()
+	}, nil
+}
+
+// `DefaultInvalidationChannel` is the Redis channel used by a `RedisBus` created without a channel.
+const DefaultInvalidationChannel = "cachego:invalidation"
+
+// The reconnect and health check settings of the `RedisBus` subscriptions.
+const (
+	redisBusPingInterval = 30 * time.Second
+	redisBusMinBackoff   = 100 * time.Millisecond
+	redisBusMaxBackoff   = 5 * time.Second
+)
+
+// The `RedisBus` type is an `InvalidationBus` over Redis pub/sub. Events are published as JSON on a
+// single channel. Each subscription keeps its own connection, pings it when idle and reconnects with
+// a backoff when it breaks. Events published while a subscription is disconnected are lost, so after
+// every reconnect the handler receives an event with `All` set and drops its local copies.
+type RedisBus struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// The `NewRedisBus` function creates a `RedisBus` on the given client, e.g. the `Cache` of a
+// `providers.RedisCache`. An empty channel selects `DefaultInvalidationChannel`. The bus doesn't own
+// the client, closing the client is up to the caller.
+func NewRedisBus(client redis.UniversalClient, channel string) *RedisBus {
+	if channel == "" {
+		channel = DefaultInvalidationChannel
+	}
+
+	return &RedisBus{client: client, channel: channel}
+}
+
+// The `Publish` function publishes the event on the channel of the bus.
+func (b *RedisBus) Publish(ctx context.Context, event InvalidationEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return b.client.Publish(ctx, b.channel, payload).Err()
+}
+
+// The `Subscribe` function subscribes to the channel of the bus and calls the handler for every event
+// from a background goroutine. It waits for the subscription to be confirmed, so it fails if Redis is
+// unreachable.
+func (b *RedisBus) Subscribe(handler func(InvalidationEvent)) (func(), error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	pubsub := b.client.Subscribe(ctx, b.channel)
+	if _, err := pubsub.Receive(ctx); err != nil {
+		cancel()
+		pubsub.Close()
+		return nil, err
+	}
+
+	done := make(chan struct{})
+	go b.receive(ctx, pubsub, handler, done)
+
+	return func() {
+		cancel()
+		pubsub.Close()
+		<-done
+	}, nil
+}
+
+// The `receive` function reads the messages of a subscription until its context is cancelled. go-redis
+// re-establishes a broken connection and resubscribes on the next read; the confirmation of such a
+// resubscription is turned into an event with `All` set.
+func (b *RedisBus) receive(ctx context.Context, pubsub *redis.PubSub, handler func(InvalidationEvent), done chan struct{}) {
+	defer close(done)
+
+	backoff := redisBusMinBackoff
+
+	for {
+		msg, err := pubsub.ReceiveTimeout(ctx, redisBusPingInterval)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				// An idle connection; a failed ping makes the next read reconnect
+				pubsub.Ping(ctx)
+				continue
+			}
+
+			slog.WarnContext(ctx, "cache invalidation subscription failed, reconnecting", slog.Any("error", err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+
+			backoff = min(2*backoff, redisBusMaxBackoff)
+			continue
+		}
+
+		backoff = redisBusMinBackoff
+
+		switch msg := msg.(type) {
+		case *redis.Subscription:
+			if msg.Kind == "subscribe" {
+				handler(InvalidationEvent{All: true})
+			}
+		case *redis.Message:
+			var event InvalidationEvent
+			if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+				slog.WarnContext(ctx, "invalid cache invalidation event", slog.Any("error", err))
+				continue
+			}
+
+			handler(event)
+		}
+	}
+}
@@ -2,12 +2,11 @@ package cachego
 
 import (
 	"context"
-	"log"
+	"sync"
 	"time"
 
 	"dario.cat/mergo"
 	"github.com/wasilak/cachego/config"
-	"github.com/wasilak/cachego/providers"
 	"go.opentelemetry.io/otel"
 )
 
@@ -24,79 +23,129 @@ import (
 // specific item in the cache. The TTL represents the amount of time that the item will remain in the
 // cache before it expires and is automatically removed. The method returns the TTL value as a
 // time.Duration,
+// @property {error} SetWithTTL - The SetWithTTL method stores an item in the cache with its own TTL
+// instead of the one from the configuration. `NoExpiration` stores the item without an expiry.
 // @property {error} ExtendTTL - ExtendTTL is a method that extends the time to live (TTL) of a cached
-// item. It takes a cacheKey string and an item interface as parameters. The cacheKey is used to
-// identify the cached item, and the item is the updated value that will be stored in the cache.
+// item. It takes a cacheKey string and a duration as parameters. The cacheKey is used to identify the
+// cached item, and the duration is added to its remaining TTL without rewriting its value.
+// @property {error} Touch - The Touch method resets the TTL of a cached item to the given duration,
+// counted from now, without rewriting its value.
+// @property {error} Delete - The Delete method removes an item from the cache. Deleting a key that
+// does not exist is not an error.
+// @property {error} DeleteMany - The DeleteMany method removes multiple items from the cache at once.
+// @property Has - The Has method reports whether an item exists in the cache and has not expired,
+// without returning its content.
+// @property GetMany - The GetMany method retrieves multiple items from the cache at once. It returns a
+// map of the found items by their cache keys; missing and expired keys are omitted.
+// @property {error} SetMany - The SetMany method stores multiple items in the cache at once with the
+// TTL from the configuration.
+// @property {error} Clear - The Clear method removes all items from the cache.
+// @property {error} Close - The Close method releases the resources held by the cache: it flushes
+// pending writes, stops background goroutines and releases file locks and connections. The cache must
+// not be used after it has been closed.
+//
+// Every method except GetConfig takes a context.Context as its first argument. The context is used as
+// the parent of the span started for the operation and is passed down to the backend, so request
+// deadlines and cancellation reach the cache (e.g. a cancelled request aborts a slow Redis call).
 type CacheInterface interface {
-	Init() error
-	Get(cacheKey string) ([]byte, bool, error)
+	Init(ctx context.Context) error
+	Get(ctx context.Context, cacheKey string) ([]byte, bool, error)
 	GetConfig() config.Config
-	Set(cacheKey string, item []byte) error
-	GetItemTTL(cacheKey string) (time.Duration, bool, error)
-	ExtendTTL(cacheKey string, item []byte) error
+	Set(ctx context.Context, cacheKey string, item []byte) error
+	SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error
+	GetItemTTL(ctx context.Context, cacheKey string) (time.Duration, bool, error)
+	ExtendTTL(ctx context.Context, cacheKey string, by time.Duration) error
+	Touch(ctx context.Context, cacheKey string, ttl time.Duration) error
+	Delete(ctx context.Context, cacheKey string) error
+	DeleteMany(ctx context.Context, cacheKeys []string) error
+	Has(ctx context.Context, cacheKey string) (bool, error)
+	GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error)
+	SetMany(ctx context.Context, items map[string][]byte) error
+	Clear(ctx context.Context) error
+	Close(ctx context.Context) error
 }
 
+// `DefaultExpiration` and `NoExpiration` are special TTL values accepted by `SetWithTTL` and `Touch`.
+// They are aliases of the values defined in the config package.
+const (
+	DefaultExpiration = config.DefaultExpiration
+	NoExpiration      = config.NoExpiration
+)
+
 // The line `var CacheInstance CacheInterface` is declaring a variable named `CacheInstance` of type
 // `CacheInterface`. This variable will be used to store an instance of a cache that implements the
-// `CacheInterface` interface.
+// `CacheInterface` interface. It is only set by `CacheInit`; caches created with `New` are not stored
+// here.
 var CacheInstance CacheInterface
 
-// The function `CacheInit` initializes and returns a cache instance based on the provided
-// configuration.
-func CacheInit(ctx context.Context, cacheConfig config.Config) (CacheInterface, error) {
+// `cacheInstanceMu` guards assignments to `CacheInstance` made by concurrent `CacheInit` calls.
+var cacheInstanceMu sync.Mutex
+
+// The function `New` creates and initializes a new, fully independent cache instance based on the
+// provided configuration. The defaults from `config.DefaultConfig` are applied to a copy, so neither
+// the package-level defaults nor any previously created instance are modified, and `New` is safe to
+// call concurrently (e.g. to build a memory cache and a Redis cache in the same binary). An unknown
+// cache type or a failed initialization results in an `*InitError`, wrapping `ErrUnknownProvider` for
+// the former. A `Tracer` set in the configuration is used by the provider, otherwise a tracer named
+// after the cache type is used. The
+// provider is looked up by `Config.Type` among the providers registered with `RegisterProvider`.
+func New(ctx context.Context, cacheConfig config.Config) (CacheInterface, error) {
 	tracer := otel.Tracer("Cache")
-	_, span := tracer.Start(ctx, "CacheInit")
+	ctx, span := tracer.Start(ctx, "New")
 	defer span.End()
 
-	err := mergo.Merge(&config.DefaultConfig, cacheConfig, mergo.WithOverride)
+	cfg := config.DefaultConfig
+
+	err := mergo.Merge(&cfg, cacheConfig, mergo.WithOverride)
 	if err != nil {
 		return nil, err
 	}
 
-	ttl, err := time.ParseDuration(config.DefaultConfig.Expiration)
+	ttl, err := time.ParseDuration(cfg.Expiration)
 	if err != nil {
 		return nil, err
 	}
 
-	config.DefaultConfig.CTX = ctx
-	config.DefaultConfig.TTL = ttl
-
-	switch config.DefaultConfig.Type {
-	case "memory":
-		{
-			config.DefaultConfig.Tracer = otel.Tracer("GoCache")
-			CacheInstance = &providers.GoCache{
-				Config: config.DefaultConfig,
-			}
-		}
-
-	case "file", "badger":
-		{
-			config.DefaultConfig.Tracer = otel.Tracer("FileCache")
-			CacheInstance = &providers.BadgerCache{
-				Path:   config.DefaultConfig.Path,
-				Config: config.DefaultConfig,
-			}
-		}
-
-	case "redis":
-		{
-			config.DefaultConfig.Tracer = otel.Tracer("RedisCache")
-			CacheInstance = &providers.RedisCache{
-				Address: config.DefaultConfig.RedisHost,
-				DB:      config.DefaultConfig.RedisDB,
-				Config:  config.DefaultConfig,
-			}
-		}
-
-	default:
-		{
-			log.Fatal("No cache type selected or cache type is invalid")
-		}
+	cfg.TTL = ttl
+
+	factory, ok := lookupProvider(cfg.Type)
+	if !ok {
+		return nil, &InitError{Provider: cfg.Type, Err: ErrUnknownProvider}
+	}
+
+	if cfg.Tracer == nil {
+		cfg.Tracer = otel.Tracer(cfg.Type)
+	}
+
+	instance, err := factory(cfg)
+	if err != nil {
+		return nil, &InitError{Provider: cfg.Type, Err: err}
+	}
 
+	err = instance.Init(ctx)
+	if err != nil {
+		return nil, &InitError{Provider: cfg.Type, Err: err}
+	}
+
+	return instance, nil
+}
+
+// The function `CacheInit` initializes and returns a cache instance based on the provided
+// configuration. It is kept for compatibility: it creates the cache with `New` and additionally stores
+// it in the package-level `CacheInstance` variable.
+func CacheInit(ctx context.Context, cacheConfig config.Config) (CacheInterface, error) {
+	tracer := otel.Tracer("Cache")
+	ctx, span := tracer.Start(ctx, "CacheInit")
+	defer span.End()
+
+	instance, err := New(ctx, cacheConfig)
+	if err != nil {
+		return nil, err
 	}
 
-	CacheInstance.Init()
+	cacheInstanceMu.Lock()
+	CacheInstance = instance
+	cacheInstanceMu.Unlock()
 
-	return CacheInstance, nil
+	return instance, nil
 }
@@ -0,0 +1,31 @@
+package cachego
+
+import (
+	"context"
+	"testing"
+
+	"github.com/wasilak/cachego/config"
+)
+
+// newTestCache creates a cache with New, a memory cache with a one minute TTL unless the configuration
+// says otherwise, and closes it when the test ends.
+func newTestCache(t *testing.T, cfg config.Config) CacheInterface {
+	t.Helper()
+
+	if cfg.Type == "" {
+		cfg.Type = "memory"
+	}
+
+	if cfg.Expiration == "" {
+		cfg.Expiration = "1m"
+	}
+
+	cache, err := New(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	t.Cleanup(func() { cache.Close(context.Background()) })
+
+	return cache
+}
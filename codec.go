@@ -0,0 +1,99 @@
+package cachego
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/vmihailenco/msgpack/v5"
+	"google.golang.org/protobuf/proto"
+)
+
+// The `Codec` interface is used by `Typed` to convert values to and from the raw bytes stored by the
+// cache providers.
+// @property {error} Marshal - The Marshal method encodes the given value into bytes.
+// @property {error} Unmarshal - The Unmarshal method decodes the given bytes into the value pointed to
+// by `v`.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// The `JSONCodec` type encodes values as JSON using the `encoding/json` package.
+type JSONCodec struct{}
+
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// The `GobCodec` type encodes values using the `encoding/gob` package. Interface values have to be
+// registered with `gob.Register` before they can be encoded.
+type GobCodec struct{}
+
+func (GobCodec) Marshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v any) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+// The `MsgpackCodec` type encodes values as MessagePack using the `github.com/vmihailenco/msgpack`
+// package.
+type MsgpackCodec struct{}
+
+func (MsgpackCodec) Marshal(v any) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v any) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+// The `ProtoCodec` type encodes protocol buffer messages using the `google.golang.org/protobuf/proto`
+// package. It is meant to be used with a `Typed` cache of a message pointer type, e.g.
+// `Typed[*pb.User]`; other values result in an error.
+type ProtoCodec struct{}
+
+func (ProtoCodec) Marshal(v any) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("cachego: ProtoCodec cannot marshal %T, it does not implement proto.Message", v)
+	}
+
+	return proto.Marshal(message)
+}
+
+func (ProtoCodec) Unmarshal(data []byte, v any) error {
+	if message, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, message)
+	}
+
+	// `Typed` decodes into a pointer to its value, so for a `Typed[*pb.User]` v is a `**pb.User` that
+	// has to point to an allocated message first.
+	value := reflect.ValueOf(v)
+	if value.Kind() == reflect.Pointer && !value.IsNil() && value.Elem().Kind() == reflect.Pointer {
+		target := value.Elem()
+		if target.IsNil() {
+			target.Set(reflect.New(target.Type().Elem()))
+		}
+
+		if message, ok := target.Interface().(proto.Message); ok {
+			return proto.Unmarshal(data, message)
+		}
+	}
+
+	return fmt.Errorf("cachego: ProtoCodec cannot unmarshal into %T, it does not implement proto.Message", v)
+}
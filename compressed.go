@@ -0,0 +1,436 @@
+package cachego
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/wasilak/cachego/providers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// `compressionMagic` starts the header of every value written by `CompressedCache`, followed by the ID
+// of the algorithm. Values without a header with a known algorithm ID were written without the decorator
+// and are returned unchanged.
+var compressionMagic = []byte{0x00, 'c', 'z'}
+
+// The compression algorithm IDs stored in the header after `compressionMagic`.
+const (
+	compressionNone byte = iota
+	compressionGzip
+	compressionSnappy
+	compressionZstd
+)
+
+// `compressionAlgorithms` maps the algorithm names accepted in `CompressionConfig` to their IDs.
+var compressionAlgorithms = map[string]byte{
+	"gzip":   compressionGzip,
+	"snappy": compressionSnappy,
+	"zstd":   compressionZstd,
+}
+
+// `DefaultMaxDecompressedSize` is the size limit of a decompressed value used when
+// `CompressionConfig.MaxSize` is zero.
+const DefaultMaxDecompressedSize = 64 << 20
+
+// `ErrDecompress` is returned by `CompressedCache` when a cached value has a compression header but
+// can't be decompressed, or decompresses to more than the configured `MaxSize`.
+var ErrDecompress = errors.New("cachego: failed to decompress cached item")
+
+// `errDecompressedTooLarge` is wrapped in `ErrDecompress` for values larger than `MaxSize`.
+var errDecompressedTooLarge = errors.New("decompressed item exceeds the maximum size")
+
+// The `CompressionConfig` type represents the configuration of a `CompressedCache`.
+// @property {string} Algorithm - The `Algorithm` property selects the compression algorithm used for
+// new values: "zstd", "snappy" or "gzip". Values are always read back with the algorithm they were
+// written with, so it can be changed at any time.
+// @property {int} Threshold - The `Threshold` property is the size in bytes from which values are
+// compressed. Smaller values are stored uncompressed, as compressing them rarely pays off.
+// @property {int} MaxSize - The `MaxSize` property is the maximum size in bytes of a decompressed value,
+// so a small corrupted or malicious value can't exhaust the memory when it is read. Larger values fail
+// with `ErrDecompress`. Zero uses `DefaultMaxDecompressedSize`.
+type CompressionConfig struct {
+	Algorithm string
+	Threshold int
+	MaxSize   int
+}
+
+// The `CompressedCache` type is a decorator around any `CacheInterface` that transparently compresses
+// values above a size threshold. Every value it writes is tagged with a header naming the algorithm,
+// including the values stored uncompressed, so all of them read back correctly. Values written to the
+// underlying cache without the decorator are returned unchanged, unless they happen to start with the
+// header magic `\x00cz` followed by a valid algorithm ID: such a value is decoded as if the decorator had
+// written it, and reads back truncated or fails with `ErrDecompress`. Text values such as JSON never
+// start with a zero byte; caches holding arbitrary binary values should be cleared before the decorator
+// is added. The sizes and compression ratio are recorded as span attributes.
+//
+// When combined with `EncryptedCache`, the compressed cache has to wrap the encrypted one, as encrypted
+// data doesn't compress.
+type CompressedCache struct {
+	CacheInterface
+	algorithm   byte
+	threshold   int
+	maxSize     int
+	tracer      trace.Tracer
+	encoder     *zstd.Encoder
+	decoder     *zstd.Decoder
+	decoderErr  error
+	decoderOnce sync.Once
+}
+
+// The `NewCompressedCache` function creates a `CompressedCache` around the given cache. It returns an
+// error for an unknown algorithm. The zstd encoder is only created when zstd is the configured
+// algorithm; the zstd decoder is created on the first zstd value read, e.g. one written before the
+// algorithm was changed.
+func NewCompressedCache(cache CacheInterface, cfg CompressionConfig) (*CompressedCache, error) {
+	algorithm, ok := compressionAlgorithms[cfg.Algorithm]
+	if !ok {
+		return nil, fmt.Errorf("cachego: unknown compression algorithm %q", cfg.Algorithm)
+	}
+
+	c := &CompressedCache{
+		CacheInterface: cache,
+		algorithm:      algorithm,
+		threshold:      cfg.Threshold,
+		maxSize:        cfg.MaxSize,
+		tracer:         otel.Tracer("CompressedCache"),
+	}
+
+	if c.maxSize <= 0 {
+		c.maxSize = DefaultMaxDecompressedSize
+	}
+
+	if algorithm == compressionZstd {
+		encoder, err := zstd.NewWriter(nil)
+		if err != nil {
+			return nil, err
+		}
+
+		c.encoder = encoder
+	}
+
+	return c, nil
+}
+
+// The `Close` function releases the zstd encoder and decoder and closes the underlying cache.
+func (c *CompressedCache) Close(ctx context.Context) error {
+	if c.encoder != nil {
+		c.encoder.Close()
+	}
+
+	// Makes sure a decoder can't be created after it has been released
+	c.decoderOnce.Do(func() {})
+
+	if c.decoder != nil {
+		c.decoder.Close()
+	}
+
+	return c.CacheInterface.Close(ctx)
+}
+
+// The `ClearPrefix` function removes all items whose cache keys start with the given prefix from the
+// underlying cache. It returns `ErrClearPrefixUnsupported` if the underlying cache does not implement
+// `PrefixClearer`.
+func (c *CompressedCache) ClearPrefix(ctx context.Context, prefix string) error {
+	clearer, ok := c.CacheInterface.(PrefixClearer)
+	if !ok {
+		return ErrClearPrefixUnsupported
+	}
+
+	return clearer.ClearPrefix(ctx, prefix)
+}
+
+// The `Lock` function acquires the distributed lock of the underlying cache used by `GetOrLoad`.
+func (c *CompressedCache) Lock(ctx context.Context, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	return lockThrough(ctx, c.CacheInterface, cacheKey, ttl)
+}
+
+// The `zstdDecoder` function returns the zstd decoder, creating it on first use. The decoder refuses to
+// allocate more than `maxSize` bytes for a value.
+func (c *CompressedCache) zstdDecoder() (*zstd.Decoder, error) {
+	c.decoderOnce.Do(func() {
+		c.decoder, c.decoderErr = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(c.maxSize)))
+	})
+
+	if c.decoder == nil && c.decoderErr == nil {
+		return nil, errors.New("compressed cache is closed")
+	}
+
+	return c.decoder, c.decoderErr
+}
+
+// The `Get` function retrieves an item from the underlying cache and decompresses it if needed.
+func (c *CompressedCache) Get(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	ctx, span := c.tracer.Start(ctx, "Get")
+	defer span.End()
+
+	item, found, err := c.CacheInterface.Get(ctx, cacheKey)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	item, err = c.decompress(span, cacheKey, item)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return item, true, nil
+}
+
+// The `Set` function compresses the item if it is above the threshold and stores it in the underlying
+// cache.
+func (c *CompressedCache) Set(ctx context.Context, cacheKey string, item []byte) error {
+	return c.SetWithTTL(ctx, cacheKey, item, DefaultExpiration)
+}
+
+// The `SetWithTTL` function compresses the item if it is above the threshold and stores it in the
+// underlying cache with its own TTL.
+func (c *CompressedCache) SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error {
+	ctx, span := c.tracer.Start(ctx, "SetWithTTL")
+	defer span.End()
+
+	item, err := c.compress(span, item)
+	if err != nil {
+		return err
+	}
+
+	return c.CacheInterface.SetWithTTL(ctx, cacheKey, item, ttl)
+}
+
+// The `GetEntry` function retrieves an item with the metadata stored by `GetOrLoad` and decompresses
+// it if needed.
+func (c *CompressedCache) GetEntry(ctx context.Context, cacheKey string) (providers.Entry, bool, error) {
+	ctx, span := c.tracer.Start(ctx, "GetEntry")
+	defer span.End()
+
+	entry, found, err := getEntry(ctx, c.CacheInterface, cacheKey)
+	if err != nil || !found {
+		return providers.Entry{}, false, err
+	}
+
+	entry.Value, err = c.decompress(span, cacheKey, entry.Value)
+	if err != nil {
+		return providers.Entry{}, false, err
+	}
+
+	return entry, true, nil
+}
+
+// The `SetEntry` function compresses the item if it is above the threshold and stores it with the
+// metadata used by `GetOrLoad`.
+func (c *CompressedCache) SetEntry(ctx context.Context, cacheKey string, entry providers.Entry, ttl time.Duration) error {
+	ctx, span := c.tracer.Start(ctx, "SetEntry")
+	defer span.End()
+
+	item, err := c.compress(span, entry.Value)
+	if err != nil {
+		return err
+	}
+
+	entry.Value = item
+
+	return setEntry(ctx, c.CacheInterface, cacheKey, entry, ttl)
+}
+
+// The `GetMany` function retrieves multiple items from the underlying cache and decompresses them.
+func (c *CompressedCache) GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error) {
+	ctx, span := c.tracer.Start(ctx, "GetMany")
+	defer span.End()
+
+	items, err := c.CacheInterface.GetMany(ctx, cacheKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	for cacheKey, item := range items {
+		if items[cacheKey], err = c.decompress(nil, cacheKey, item); err != nil {
+			return nil, err
+		}
+	}
+
+	return items, nil
+}
+
+// The `SetMany` function compresses multiple items and stores them in the underlying cache.
+func (c *CompressedCache) SetMany(ctx context.Context, items map[string][]byte) error {
+	ctx, span := c.tracer.Start(ctx, "SetMany")
+	defer span.End()
+
+	compressed := make(map[string][]byte, len(items))
+
+	for cacheKey, item := range items {
+		item, err := c.compress(nil, item)
+		if err != nil {
+			return err
+		}
+
+		compressed[cacheKey] = item
+	}
+
+	return c.CacheInterface.SetMany(ctx, compressed)
+}
+
+// The `compress` function returns the item prefixed with the compression header. Items below the
+// threshold, or that don't get smaller, are stored uncompressed. The sizes are recorded on the span if
+// it is not nil.
+func (c *CompressedCache) compress(span trace.Span, item []byte) ([]byte, error) {
+	algorithm := compressionNone
+	payload := item
+
+	if len(item) >= c.threshold {
+		compressed, err := c.encode(c.algorithm, item)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(compressed) < len(item) {
+			algorithm = c.algorithm
+			payload = compressed
+		}
+	}
+
+	stored := make([]byte, 0, len(compressionMagic)+1+len(payload))
+	stored = append(stored, compressionMagic...)
+	stored = append(stored, algorithm)
+	stored = append(stored, payload...)
+
+	if span != nil {
+		span.SetAttributes(compressionAttributes(algorithm, len(item), len(stored))...)
+	}
+
+	return stored, nil
+}
+
+// The `decompress` function strips the compression header from a stored item and decompresses it with
+// the algorithm named in the header. Items without a header, or whose header names an unknown
+// algorithm, are returned unchanged. The sizes are recorded on the span if it is not nil.
+func (c *CompressedCache) decompress(span trace.Span, cacheKey string, stored []byte) ([]byte, error) {
+	if len(stored) <= len(compressionMagic) || !bytes.HasPrefix(stored, compressionMagic) {
+		return stored, nil
+	}
+
+	algorithm := stored[len(compressionMagic)]
+	if algorithm > compressionZstd {
+		return stored, nil
+	}
+
+	payload := stored[len(compressionMagic)+1:]
+
+	item, err := c.decode(algorithm, payload)
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrDecompress, cacheKey, err)
+	}
+
+	if span != nil {
+		span.SetAttributes(compressionAttributes(algorithm, len(item), len(stored))...)
+	}
+
+	return item, nil
+}
+
+// The `encode` function compresses data with the given algorithm.
+func (c *CompressedCache) encode(algorithm byte, data []byte) ([]byte, error) {
+	switch algorithm {
+	case compressionGzip:
+		var buf bytes.Buffer
+
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			return nil, err
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+
+	case compressionSnappy:
+		return snappy.Encode(nil, data), nil
+
+	case compressionZstd:
+		return c.encoder.EncodeAll(data, nil), nil
+	}
+
+	return data, nil
+}
+
+// The `decode` function decompresses data with the given algorithm. It fails before decompressing more
+// than `maxSize` bytes.
+func (c *CompressedCache) decode(algorithm byte, data []byte) ([]byte, error) {
+	switch algorithm {
+	case compressionNone:
+		return data, nil
+
+	case compressionGzip:
+		r, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		item, err := io.ReadAll(io.LimitReader(r, int64(c.maxSize)+1))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(item) > c.maxSize {
+			return nil, errDecompressedTooLarge
+		}
+
+		return item, nil
+
+	case compressionSnappy:
+		size, err := snappy.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if size > c.maxSize {
+			return nil, errDecompressedTooLarge
+		}
+
+		return snappy.Decode(nil, data)
+
+	case compressionZstd:
+		decoder, err := c.zstdDecoder()
+		if err != nil {
+			return nil, err
+		}
+
+		return decoder.DecodeAll(data, nil)
+	}
+
+	return nil, fmt.Errorf("unknown compression algorithm %d", algorithm)
+}
+
+// The `compressionAttributes` function returns the span attributes describing a compressed item.
+func compressionAttributes(algorithm byte, size, storedSize int) []attribute.KeyValue {
+	name := "none"
+	for n, id := range compressionAlgorithms {
+		if id == algorithm {
+			name = n
+		}
+	}
+
+	ratio := 1.0
+	if storedSize > 0 {
+		ratio = float64(size) / float64(storedSize)
+	}
+
+	return []attribute.KeyValue{
+		attribute.String("cache.compression.algorithm", name),
+		attribute.Int("cache.compression.size", size),
+		attribute.Int("cache.compression.stored_size", storedSize),
+		attribute.Float64("cache.compression.ratio", ratio),
+	}
+}
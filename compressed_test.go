@@ -0,0 +1,233 @@
+package cachego
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"github.com/wasilak/cachego/providers"
+)
+
+func newTestCompressedCache(t *testing.T, cache CacheInterface, cfg CompressionConfig) *CompressedCache {
+	t.Helper()
+
+	compressed, err := NewCompressedCache(cache, cfg)
+	if err != nil {
+		t.Fatalf("NewCompressedCache() error = %v", err)
+	}
+
+	return compressed
+}
+
+// compressible is a value large enough to be compressed by every algorithm.
+var compressible = bytes.Repeat([]byte(`{"name":"alice","age":42},`), 100)
+
+func TestCompressedCacheRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		algorithm     string
+		item          []byte
+		wantAlgorithm byte
+	}{
+		{name: "gzip", algorithm: "gzip", item: compressible, wantAlgorithm: compressionGzip},
+		{name: "snappy", algorithm: "snappy", item: compressible, wantAlgorithm: compressionSnappy},
+		{name: "zstd", algorithm: "zstd", item: compressible, wantAlgorithm: compressionZstd},
+		{name: "below the threshold", algorithm: "zstd", item: []byte("small"), wantAlgorithm: compressionNone},
+		{name: "empty value", algorithm: "zstd", item: []byte{}, wantAlgorithm: compressionNone},
+		{name: "value starting with the header", algorithm: "gzip", item: []byte("\x00cz\x01"), wantAlgorithm: compressionNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newTestCache(t, config.Config{})
+			c := newTestCompressedCache(t, inner, CompressionConfig{Algorithm: tt.algorithm, Threshold: 64})
+
+			if err := c.Set(ctx, "k", tt.item); err != nil {
+				t.Fatal(err)
+			}
+
+			item, found, err := c.Get(ctx, "k")
+			if err != nil || !found || !bytes.Equal(item, tt.item) {
+				t.Errorf("Get() = %q, %v, %v, want the original item", item, found, err)
+			}
+
+			stored, _, _ := inner.Get(ctx, "k")
+			if !bytes.HasPrefix(stored, compressionMagic) || stored[len(compressionMagic)] != tt.wantAlgorithm {
+				t.Errorf("stored header = %q, want algorithm %d", stored[:min(len(stored), 4)], tt.wantAlgorithm)
+			}
+
+			if tt.wantAlgorithm != compressionNone && len(stored) >= len(tt.item) {
+				t.Errorf("stored size = %d, want less than %d", len(stored), len(tt.item))
+			}
+
+			if err := c.SetMany(ctx, map[string][]byte{"x": tt.item}); err != nil {
+				t.Fatal(err)
+			}
+
+			items, err := c.GetMany(ctx, []string{"x", "missing"})
+			if err != nil || len(items) != 1 || !bytes.Equal(items["x"], tt.item) {
+				t.Errorf("GetMany() = %q, %v, want the original item", items, err)
+			}
+		})
+	}
+}
+
+func TestCompressedCacheReadsValuesWithoutHeader(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		stored []byte
+	}{
+		{name: "plain value", stored: []byte("plain")},
+		{name: "header magic only", stored: []byte("\x00cz")},
+		{name: "unknown algorithm", stored: []byte("\x00cz\x09payload")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newTestCache(t, config.Config{})
+			c := newTestCompressedCache(t, inner, CompressionConfig{Algorithm: "zstd"})
+
+			if err := inner.Set(ctx, "k", tt.stored); err != nil {
+				t.Fatal(err)
+			}
+
+			item, found, err := c.Get(ctx, "k")
+			if err != nil || !found || !bytes.Equal(item, tt.stored) {
+				t.Errorf("Get() = %q, %v, %v, want the stored value unchanged", item, found, err)
+			}
+		})
+	}
+}
+
+// oversized returns a value that decompresses to more than the maximum size used by the tests, written
+// by a cache without the limit.
+func oversized(algorithm string) func(t *testing.T, inner CacheInterface) []byte {
+	return func(t *testing.T, inner CacheInterface) []byte {
+		writer := newTestCompressedCache(t, inner, CompressionConfig{Algorithm: algorithm})
+
+		item, err := writer.compress(nil, make([]byte, 1<<20))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return item
+	}
+}
+
+func TestCompressedCacheDecompressErrors(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		algorithm string
+		stored    func(t *testing.T, inner CacheInterface) []byte
+	}{
+		{
+			name:      "corrupted gzip",
+			algorithm: "gzip",
+			stored:    func(*testing.T, CacheInterface) []byte { return []byte("\x00cz\x01corrupted") },
+		},
+		{
+			name:      "corrupted zstd",
+			algorithm: "zstd",
+			stored:    func(*testing.T, CacheInterface) []byte { return []byte("\x00cz\x03corrupted") },
+		},
+		{name: "gzip above the maximum size", algorithm: "gzip", stored: oversized("gzip")},
+		{name: "snappy above the maximum size", algorithm: "snappy", stored: oversized("snappy")},
+		{name: "zstd above the maximum size", algorithm: "zstd", stored: oversized("zstd")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newTestCache(t, config.Config{})
+			c := newTestCompressedCache(t, inner, CompressionConfig{Algorithm: tt.algorithm, MaxSize: 1 << 16})
+
+			if err := inner.Set(ctx, "k", tt.stored(t, inner)); err != nil {
+				t.Fatal(err)
+			}
+
+			item, found, err := c.Get(ctx, "k")
+			if !errors.Is(err, ErrDecompress) || found || item != nil {
+				t.Errorf("Get() = %q, %v, %v, want nil, false, ErrDecompress", item, found, err)
+			}
+
+			if items, err := c.GetMany(ctx, []string{"k"}); !errors.Is(err, ErrDecompress) || items != nil {
+				t.Errorf("GetMany() = %q, %v, want nil, ErrDecompress", items, err)
+			}
+		})
+	}
+}
+
+func TestCompressedCacheEntries(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCompressedCache(t, newTestCache(t, config.Config{}), CompressionConfig{Algorithm: "zstd"})
+
+	entry := providers.Entry{Value: compressible, SoftExpiry: time.Now().Add(time.Minute), LoadDuration: time.Second}
+	if err := c.SetEntry(ctx, "k", entry, 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	got, found, err := c.GetEntry(ctx, "k")
+	if err != nil || !found || !bytes.Equal(got.Value, entry.Value) || !got.SoftExpiry.Equal(entry.SoftExpiry) || got.LoadDuration != entry.LoadDuration {
+		t.Errorf("GetEntry() = %v, %v, want the stored entry", found, err)
+	}
+
+	if item, found, err := c.Get(ctx, "k"); err != nil || !found || !bytes.Equal(item, compressible) {
+		t.Errorf("Get() = %v, %v, want the item of the entry", found, err)
+	}
+}
+
+func TestCompressedCacheClearPrefix(t *testing.T) {
+	ctx := context.Background()
+	c := newTestCompressedCache(t, newTestCache(t, config.Config{}), CompressionConfig{Algorithm: "snappy"})
+
+	if err := c.SetMany(ctx, map[string][]byte{"user:1": compressible, "order:1": compressible}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.ClearPrefix(ctx, "user:"); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"user:1": false, "order:1": true} {
+		if found, err := c.Has(ctx, key); err != nil || found != want {
+			t.Errorf("Has(%q) = %v, %v, want %v", key, found, err, want)
+		}
+	}
+}
+
+func TestCompressedCacheClose(t *testing.T) {
+	ctx := context.Background()
+
+	// The view is not closed with the decorator, so the item can still be read after Close()
+	inner := NewNamespacedCache(newTestCache(t, config.Config{}), "ns")
+
+	writer := newTestCompressedCache(t, inner, CompressionConfig{Algorithm: "zstd"})
+	if err := writer.Set(ctx, "k", compressible); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestCompressedCache(t, inner, CompressionConfig{Algorithm: "gzip"})
+	if c.encoder != nil {
+		t.Error("a zstd encoder was created for gzip")
+	}
+
+	if err := c.Close(ctx); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if _, _, err := c.Get(ctx, "k"); !errors.Is(err, ErrDecompress) {
+		t.Errorf("Get() of a zstd value after Close() error = %v, want ErrDecompress", err)
+	}
+
+	if err := writer.Close(ctx); err != nil {
+		t.Errorf("Close() of the writer error = %v", err)
+	}
+}
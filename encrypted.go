@@ -0,0 +1,317 @@
+package cachego
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/wasilak/cachego/providers"
+)
+
+// `ErrDecrypt` is returned by `EncryptedCache` when a cached value can't be decrypted, because it was
+// tampered with, is not encrypted or was encrypted with an unknown key. The cache fails closed: such a
+// value is never returned to the caller.
+var ErrDecrypt = errors.New("cachego: failed to decrypt cached item")
+
+// `encryptedFormatVersion` is the first byte of every value written by `EncryptedCache`.
+const encryptedFormatVersion byte = 1
+
+// The `EncryptionConfig` type represents the configuration of an `EncryptedCache`.
+// @property Keys - The `Keys` property maps key IDs to AES keys, which must be 16, 24 or 32 bytes
+// long. Values are decrypted with the key whose ID they were written with, so old keys can be kept
+// here for reading while new values are written with a new one.
+// @property {string} ActiveKeyID - The `ActiveKeyID` property is the ID of the key used to encrypt new
+// values.
+// @property {bool} HashKeys - The `HashKeys` property replaces cache keys with their HMAC-SHA256, so key
+// names (which may contain PII) don't leak to the backend.
+// @property HMACKey - The `HMACKey` property is the secret used to hash cache keys when `HashKeys` is
+// enabled.
+type EncryptionConfig struct {
+	Keys        map[string][]byte
+	ActiveKeyID string
+	HashKeys    bool
+	HMACKey     []byte
+}
+
+// The `EncryptedCache` type is a decorator around any `CacheInterface` that transparently encrypts
+// values with AES-GCM before they reach the backend, so it works with memory, badger and redis alike.
+// Every stored value is prefixed with the ID of the key it was encrypted with and authenticated
+// together with its cache key, so values can't be modified or moved to another key unnoticed.
+type EncryptedCache struct {
+	CacheInterface
+	aeads       map[string]cipher.AEAD
+	activeKeyID string
+	hashKeys    bool
+	hmacKey     []byte
+}
+
+// The `NewEncryptedCache` function creates an `EncryptedCache` around the given cache. It returns an
+// error if the active key is missing or any key has an invalid length.
+func NewEncryptedCache(cache CacheInterface, cfg EncryptionConfig) (*EncryptedCache, error) {
+	if _, ok := cfg.Keys[cfg.ActiveKeyID]; !ok {
+		return nil, fmt.Errorf("cachego: active encryption key %q is not configured", cfg.ActiveKeyID)
+	}
+
+	if cfg.HashKeys && len(cfg.HMACKey) == 0 {
+		return nil, errors.New("cachego: HMACKey is required when HashKeys is enabled")
+	}
+
+	aeads := make(map[string]cipher.AEAD, len(cfg.Keys))
+
+	for keyID, key := range cfg.Keys {
+		if len(keyID) > 255 {
+			return nil, fmt.Errorf("cachego: encryption key ID %q is longer than 255 bytes", keyID)
+		}
+
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, fmt.Errorf("cachego: invalid encryption key %q: %w", keyID, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		aeads[keyID] = aead
+	}
+
+	return &EncryptedCache{
+		CacheInterface: cache,
+		aeads:          aeads,
+		activeKeyID:    cfg.ActiveKeyID,
+		hashKeys:       cfg.HashKeys,
+		hmacKey:        cfg.HMACKey,
+	}, nil
+}
+
+// The `Get` function retrieves an item from the underlying cache and decrypts it. An item that fails to
+// decrypt results in `ErrDecrypt`.
+func (c *EncryptedCache) Get(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	storedKey := c.storedKey(cacheKey)
+
+	item, found, err := c.CacheInterface.Get(ctx, storedKey)
+	if err != nil || !found {
+		return nil, false, err
+	}
+
+	item, err = c.decrypt(storedKey, item)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return item, true, nil
+}
+
+// The `Set` function encrypts the item and stores it in the underlying cache.
+func (c *EncryptedCache) Set(ctx context.Context, cacheKey string, item []byte) error {
+	return c.SetWithTTL(ctx, cacheKey, item, DefaultExpiration)
+}
+
+// The `SetWithTTL` function encrypts the item and stores it in the underlying cache with its own TTL.
+func (c *EncryptedCache) SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error {
+	storedKey := c.storedKey(cacheKey)
+
+	item, err := c.encrypt(storedKey, item)
+	if err != nil {
+		return err
+	}
+
+	return c.CacheInterface.SetWithTTL(ctx, storedKey, item, ttl)
+}
+
+// The `GetEntry` function retrieves an item with the metadata stored by `GetOrLoad` and decrypts it.
+func (c *EncryptedCache) GetEntry(ctx context.Context, cacheKey string) (providers.Entry, bool, error) {
+	storedKey := c.storedKey(cacheKey)
+
+	entry, found, err := getEntry(ctx, c.CacheInterface, storedKey)
+	if err != nil || !found {
+		return providers.Entry{}, false, err
+	}
+
+	entry.Value, err = c.decrypt(storedKey, entry.Value)
+	if err != nil {
+		return providers.Entry{}, false, err
+	}
+
+	return entry, true, nil
+}
+
+// The `SetEntry` function encrypts the item and stores it with the metadata used by `GetOrLoad`. The
+// metadata is not encrypted.
+func (c *EncryptedCache) SetEntry(ctx context.Context, cacheKey string, entry providers.Entry, ttl time.Duration) error {
+	storedKey := c.storedKey(cacheKey)
+
+	item, err := c.encrypt(storedKey, entry.Value)
+	if err != nil {
+		return err
+	}
+
+	entry.Value = item
+
+	return setEntry(ctx, c.CacheInterface, storedKey, entry, ttl)
+}
+
+// The `GetItemTTL` function returns the remaining TTL of the item in the underlying cache.
+func (c *EncryptedCache) GetItemTTL(ctx context.Context, cacheKey string) (time.Duration, bool, error) {
+	return c.CacheInterface.GetItemTTL(ctx, c.storedKey(cacheKey))
+}
+
+// The `ExtendTTL` function extends the TTL of the item in the underlying cache.
+func (c *EncryptedCache) ExtendTTL(ctx context.Context, cacheKey string, by time.Duration) error {
+	return c.CacheInterface.ExtendTTL(ctx, c.storedKey(cacheKey), by)
+}
+
+// The `Touch` function resets the TTL of the item in the underlying cache.
+func (c *EncryptedCache) Touch(ctx context.Context, cacheKey string, ttl time.Duration) error {
+	return c.CacheInterface.Touch(ctx, c.storedKey(cacheKey), ttl)
+}
+
+// The `Delete` function removes the item from the underlying cache.
+func (c *EncryptedCache) Delete(ctx context.Context, cacheKey string) error {
+	return c.CacheInterface.Delete(ctx, c.storedKey(cacheKey))
+}
+
+// The `DeleteMany` function removes multiple items from the underlying cache.
+func (c *EncryptedCache) DeleteMany(ctx context.Context, cacheKeys []string) error {
+	storedKeys := make([]string, len(cacheKeys))
+	for i, cacheKey := range cacheKeys {
+		storedKeys[i] = c.storedKey(cacheKey)
+	}
+
+	return c.CacheInterface.DeleteMany(ctx, storedKeys)
+}
+
+// The `Has` function reports whether the item exists in the underlying cache.
+func (c *EncryptedCache) Has(ctx context.Context, cacheKey string) (bool, error) {
+	return c.CacheInterface.Has(ctx, c.storedKey(cacheKey))
+}
+
+// The `GetMany` function retrieves multiple items from the underlying cache and decrypts them. If any
+// item fails to decrypt, `ErrDecrypt` is returned and no items are returned.
+func (c *EncryptedCache) GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error) {
+	storedKeys := make([]string, len(cacheKeys))
+	for i, cacheKey := range cacheKeys {
+		storedKeys[i] = c.storedKey(cacheKey)
+	}
+
+	stored, err := c.CacheInterface.GetMany(ctx, storedKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[string][]byte, len(stored))
+
+	for i, storedKey := range storedKeys {
+		item, found := stored[storedKey]
+		if !found {
+			continue
+		}
+
+		item, err := c.decrypt(storedKey, item)
+		if err != nil {
+			return nil, err
+		}
+
+		items[cacheKeys[i]] = item
+	}
+
+	return items, nil
+}
+
+// The `SetMany` function encrypts multiple items and stores them in the underlying cache.
+func (c *EncryptedCache) SetMany(ctx context.Context, items map[string][]byte) error {
+	stored := make(map[string][]byte, len(items))
+
+	for cacheKey, item := range items {
+		storedKey := c.storedKey(cacheKey)
+
+		item, err := c.encrypt(storedKey, item)
+		if err != nil {
+			return err
+		}
+
+		stored[storedKey] = item
+	}
+
+	return c.CacheInterface.SetMany(ctx, stored)
+}
+
+// The `Lock` function acquires the distributed lock of the underlying cache used by `GetOrLoad`, for
+// the key the item is stored under.
+func (c *EncryptedCache) Lock(ctx context.Context, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	return lockThrough(ctx, c.CacheInterface, c.storedKey(cacheKey), ttl)
+}
+
+// The `storedKey` function returns the key the item is stored under in the underlying cache: the
+// hex-encoded HMAC-SHA256 of the cache key if `HashKeys` is enabled, the cache key itself otherwise.
+func (c *EncryptedCache) storedKey(cacheKey string) string {
+	if !c.hashKeys {
+		return cacheKey
+	}
+
+	mac := hmac.New(sha256.New, c.hmacKey)
+	mac.Write([]byte(cacheKey))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// The `encrypt` function encrypts an item with the active key. The result has the format
+// `version | key ID length | key ID | nonce | ciphertext`, and the stored key is used as additional
+// authenticated data.
+func (c *EncryptedCache) encrypt(storedKey string, item []byte) ([]byte, error) {
+	aead := c.aeads[c.activeKeyID]
+
+	header := make([]byte, 0, 2+len(c.activeKeyID)+aead.NonceSize())
+	header = append(header, encryptedFormatVersion, byte(len(c.activeKeyID)))
+	header = append(header, c.activeKeyID...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	header = append(header, nonce...)
+
+	return aead.Seal(header, nonce, item, []byte(storedKey)), nil
+}
+
+// The `decrypt` function decrypts an item written by `encrypt` with the key it was encrypted with.
+func (c *EncryptedCache) decrypt(storedKey string, data []byte) ([]byte, error) {
+	if len(data) < 2 || data[0] != encryptedFormatVersion {
+		return nil, fmt.Errorf("%w %q: unknown format", ErrDecrypt, storedKey)
+	}
+
+	keyIDLength := int(data[1])
+	if len(data) < 2+keyIDLength {
+		return nil, fmt.Errorf("%w %q: truncated value", ErrDecrypt, storedKey)
+	}
+
+	keyID := string(data[2 : 2+keyIDLength])
+	data = data[2+keyIDLength:]
+
+	aead, ok := c.aeads[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w %q: unknown key %q", ErrDecrypt, storedKey, keyID)
+	}
+
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w %q: truncated value", ErrDecrypt, storedKey)
+	}
+
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+
+	item, err := aead.Open(nil, nonce, ciphertext, []byte(storedKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w %q: %v", ErrDecrypt, storedKey, err)
+	}
+
+	return item, nil
+}
@@ -0,0 +1,210 @@
+package cachego
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wasilak/cachego/config"
+)
+
+func newTestEncryptedCache(t *testing.T, cache CacheInterface, cfg EncryptionConfig) *EncryptedCache {
+	t.Helper()
+
+	encrypted, err := NewEncryptedCache(cache, cfg)
+	if err != nil {
+		t.Fatalf("NewEncryptedCache() error = %v", err)
+	}
+
+	return encrypted
+}
+
+var (
+	testKeyA = bytes.Repeat([]byte{0xa}, 32)
+	testKeyB = bytes.Repeat([]byte{0xb}, 16)
+)
+
+func TestEncryptedCacheRoundTrip(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		cfg  EncryptionConfig
+	}{
+		{name: "AES-256", cfg: EncryptionConfig{Keys: map[string][]byte{"a": testKeyA}, ActiveKeyID: "a"}},
+		{name: "AES-128", cfg: EncryptionConfig{Keys: map[string][]byte{"b": testKeyB}, ActiveKeyID: "b"}},
+		{name: "hashed keys", cfg: EncryptionConfig{Keys: map[string][]byte{"a": testKeyA}, ActiveKeyID: "a", HashKeys: true, HMACKey: []byte("hmac")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newTestCache(t, config.Config{})
+			c := newTestEncryptedCache(t, inner, tt.cfg)
+
+			if err := c.Set(ctx, "k", []byte("secret")); err != nil {
+				t.Fatal(err)
+			}
+
+			item, found, err := c.Get(ctx, "k")
+			if err != nil || !found || string(item) != "secret" {
+				t.Fatalf("Get() = %q, %v, %v, want %q, true, nil", item, found, err, "secret")
+			}
+
+			stored, _, _ := inner.Get(ctx, c.storedKey("k"))
+			if bytes.Contains(stored, []byte("secret")) {
+				t.Error("the stored value contains the plaintext")
+			}
+
+			if err := c.SetMany(ctx, map[string][]byte{"x": []byte("1"), "y": []byte("2")}); err != nil {
+				t.Fatal(err)
+			}
+
+			items, err := c.GetMany(ctx, []string{"x", "y", "missing"})
+			if err != nil || len(items) != 2 || string(items["x"]) != "1" || string(items["y"]) != "2" {
+				t.Errorf("GetMany() = %q, %v, want x=1 and y=2", items, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedCacheFailsClosed(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		tamper func(t *testing.T, inner CacheInterface)
+	}{
+		{
+			name: "tampered ciphertext",
+			tamper: func(t *testing.T, inner CacheInterface) {
+				stored, _, _ := inner.Get(ctx, "k")
+				stored = bytes.Clone(stored)
+				stored[len(stored)-1] ^= 0xff
+				inner.Set(ctx, "k", stored)
+			},
+		},
+		{
+			name: "truncated value",
+			tamper: func(t *testing.T, inner CacheInterface) {
+				stored, _, _ := inner.Get(ctx, "k")
+				inner.Set(ctx, "k", stored[:5])
+			},
+		},
+		{
+			name: "unencrypted value",
+			tamper: func(t *testing.T, inner CacheInterface) {
+				inner.Set(ctx, "k", []byte("plain"))
+			},
+		},
+		{
+			name: "value moved from another key",
+			tamper: func(t *testing.T, inner CacheInterface) {
+				stored, _, _ := inner.Get(ctx, "other")
+				inner.Set(ctx, "k", stored)
+			},
+		},
+		{
+			name: "unknown key ID",
+			tamper: func(t *testing.T, inner CacheInterface) {
+				other := newTestEncryptedCache(t, inner, EncryptionConfig{Keys: map[string][]byte{"b": testKeyB}, ActiveKeyID: "b"})
+				other.Set(ctx, "k", []byte("written with another key"))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inner := newTestCache(t, config.Config{})
+			c := newTestEncryptedCache(t, inner, EncryptionConfig{Keys: map[string][]byte{"a": testKeyA}, ActiveKeyID: "a"})
+
+			c.Set(ctx, "k", []byte("secret"))
+			c.Set(ctx, "other", []byte("other secret"))
+
+			tt.tamper(t, inner)
+
+			item, found, err := c.Get(ctx, "k")
+			if !errors.Is(err, ErrDecrypt) || found || item != nil {
+				t.Errorf("Get() = %q, %v, %v, want nil, false, ErrDecrypt", item, found, err)
+			}
+
+			items, err := c.GetMany(ctx, []string{"k", "other"})
+			if !errors.Is(err, ErrDecrypt) || items != nil {
+				t.Errorf("GetMany() = %q, %v, want nil, ErrDecrypt", items, err)
+			}
+		})
+	}
+}
+
+func TestEncryptedCacheKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	inner := newTestCache(t, config.Config{})
+
+	before := newTestEncryptedCache(t, inner, EncryptionConfig{Keys: map[string][]byte{"a": testKeyA}, ActiveKeyID: "a"})
+	if err := before.Set(ctx, "old", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	after := newTestEncryptedCache(t, inner, EncryptionConfig{
+		Keys:        map[string][]byte{"a": testKeyA, "b": testKeyB},
+		ActiveKeyID: "b",
+	})
+	if err := after.Set(ctx, "new", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{"old": "v1", "new": "v2"} {
+		item, found, err := after.Get(ctx, key)
+		if err != nil || !found || string(item) != want {
+			t.Errorf("Get(%q) = %q, %v, %v, want %q, true, nil", key, item, found, err, want)
+		}
+	}
+
+	if _, _, err := before.Get(ctx, "new"); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Get() of a value written with a key that was removed = %v, want ErrDecrypt", err)
+	}
+}
+
+func TestEncryptedCacheHashKeys(t *testing.T) {
+	ctx := context.Background()
+	inner := newTestCache(t, config.Config{})
+	c := newTestEncryptedCache(t, inner, EncryptionConfig{
+		Keys:        map[string][]byte{"a": testKeyA},
+		ActiveKeyID: "a",
+		HashKeys:    true,
+		HMACKey:     []byte("hmac"),
+	})
+
+	if err := c.Set(ctx, "user@example.com", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, _ := inner.Has(ctx, "user@example.com"); found {
+		t.Error("the cache key is stored in plain text")
+	}
+
+	if found, _ := inner.Has(ctx, c.storedKey("user@example.com")); !found {
+		t.Error("the item is not stored under the hashed key")
+	}
+
+	item, found, err := c.Get(ctx, "user@example.com")
+	if err != nil || !found || string(item) != "v" {
+		t.Errorf("Get() = %q, %v, %v, want %q, true, nil", item, found, err, "v")
+	}
+}
+
+func TestNewEncryptedCacheInvalidConfig(t *testing.T) {
+	inner := newTestCache(t, config.Config{})
+
+	tests := map[string]EncryptionConfig{
+		"missing active key": {Keys: map[string][]byte{"a": testKeyA}, ActiveKeyID: "b"},
+		"invalid key length": {Keys: map[string][]byte{"a": []byte("short")}, ActiveKeyID: "a"},
+		"missing HMAC key":   {Keys: map[string][]byte{"a": testKeyA}, ActiveKeyID: "a", HashKeys: true},
+	}
+
+	for name, cfg := range tests {
+		if _, err := NewEncryptedCache(inner, cfg); err == nil {
+			t.Errorf("%s: NewEncryptedCache() error = nil", name)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package cachego
+
+import (
+	"errors"
+	"fmt"
+)
+
+// `ErrUnknownProvider` is returned by `New` and `CacheInit` when `Config.Type` does not name a known
+// cache provider.
+var ErrUnknownProvider = errors.New("cachego: no cache type selected or cache type is invalid")
+
+// The `InitError` type is returned by `New` and `CacheInit` when a provider fails to initialize, e.g.
+// because the Badger path cannot be opened or the Redis host is unreachable. It wraps the error
+// returned by the provider, so it can be inspected with `errors.Is` and `errors.As`.
+// @property {string} Provider - The `Provider` property is the cache type that failed to initialize.
+// @property {error} Err - The `Err` property is the error returned by the provider's `Init` method.
+type InitError struct {
+	Provider string
+	Err      error
+}
+
+func (e *InitError) Error() string {
+	return fmt.Sprintf("cachego: failed to initialize %q cache: %v", e.Provider, e.Err)
+}
+
+func (e *InitError) Unwrap() error {
+	return e.Err
+}
+
+// `ErrClearPrefixUnsupported` is returned by `NamespacedCache.Clear` when the underlying cache can't
+// remove items by key prefix, i.e. it does not implement `PrefixClearer`.
+var ErrClearPrefixUnsupported = errors.New("cachego: cache does not support clearing by key prefix")
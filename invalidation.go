@@ -0,0 +1,253 @@
+package cachego
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/wasilak/cachego/providers"
+)
+
+// The `InvalidationEvent` type represents a change made to a cache on one instance that the other
+// instances have to apply to their local copies.
+// @property {string} Source - The `Source` property identifies the cache that published the event, so
+// it can ignore its own events. It is set by the publisher.
+// @property {[]string} Keys - The `Keys` property lists the cache keys that were written or deleted.
+// @property {string} Prefix - The `Prefix` property is set when all items whose cache keys start with
+// it were removed.
+// @property {bool} All - The `All` property is set when the cache was cleared, or when events may have
+// been lost (e.g. after a reconnect) and all local copies must be dropped.
+type InvalidationEvent struct {
+	Source string   `json:"source,omitempty"`
+	Keys   []string `json:"keys,omitempty"`
+	Prefix string   `json:"prefix,omitempty"`
+	All    bool     `json:"all,omitempty"`
+}
+
+// The `InvalidationBus` interface distributes invalidation events between cache instances, e.g. the
+// in-memory caches of several pods.
+// @property {error} Publish - The Publish method sends an event to all subscribers, including the
+// ones of the publishing instance.
+// @property Subscribe - The Subscribe method registers a handler called for every event and returns a
+// function that cancels the subscription. Events published after Subscribe returns are delivered to
+// the handler.
+type InvalidationBus interface {
+	Publish(ctx context.Context, event InvalidationEvent) error
+	Subscribe(handler func(InvalidationEvent)) (func(), error)
+}
+
+// The `invalidator` type connects a local cache to an invalidation bus: it publishes the changes made
+// through it and applies the events of other instances to the local cache. A nil invalidator does
+// nothing, so it can be used unconditionally.
+// @property bus - The `bus` property is the bus the events are published to and received from.
+// @property source - The `source` property is the random ID identifying the events of this invalidator.
+// @property unsubscribe - The `unsubscribe` property cancels the subscription to the bus.
+type invalidator struct {
+	bus         InvalidationBus
+	source      string
+	unsubscribe func()
+}
+
+// The `newInvalidator` function subscribes to the bus and applies the events published by other
+// instances to the local cache. Written and deleted keys are deleted from the local cache, so the next
+// read fetches the current value.
+func newInvalidator(bus InvalidationBus, local CacheInterface) (*invalidator, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+
+	i := &invalidator{bus: bus, source: hex.EncodeToString(id)}
+
+	unsubscribe, err := bus.Subscribe(func(event InvalidationEvent) {
+		if event.Source == i.source {
+			return
+		}
+
+		applyInvalidation(context.Background(), local, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	i.unsubscribe = unsubscribe
+
+	return i, nil
+}
+
+// The `applyInvalidation` function removes the items affected by an event from the local cache.
+func applyInvalidation(ctx context.Context, local CacheInterface, event InvalidationEvent) {
+	var err error
+
+	switch {
+	case event.All:
+		err = local.Clear(ctx)
+	case event.Prefix != "":
+		if clearer, ok := local.(PrefixClearer); ok {
+			err = clearer.ClearPrefix(ctx, event.Prefix)
+		} else {
+			err = local.Clear(ctx)
+		}
+	case len(event.Keys) > 0:
+		err = local.DeleteMany(ctx, event.Keys)
+	}
+
+	if err != nil {
+		slog.WarnContext(ctx, "failed to apply cache invalidation", slog.Any("error", err))
+	}
+}
+
+// The `publish` function publishes an event for the changes made through the local cache. A failure is
+// logged rather than returned, as the change itself has already been made.
+func (i *invalidator) publish(ctx context.Context, event InvalidationEvent) {
+	if i == nil {
+		return
+	}
+
+	event.Source = i.source
+
+	if err := i.bus.Publish(ctx, event); err != nil {
+		slog.WarnContext(ctx, "failed to publish cache invalidation", slog.Any("error", err))
+	}
+}
+
+// The `close` function cancels the subscription to the bus.
+func (i *invalidator) close() {
+	if i == nil {
+		return
+	}
+
+	i.unsubscribe()
+}
+
+// The `InvalidatingCache` type is a decorator around a local cache, typically a `GoCache` per pod,
+// that keeps the copies of other instances consistent: every write and delete made through it is
+// published on an `InvalidationBus`, and the events of other instances evict the affected keys from
+// the local cache. TTL changes are not published, as they don't change the values.
+type InvalidatingCache struct {
+	CacheInterface
+	invalidator *invalidator
+}
+
+// The `NewInvalidatingCache` function creates an `InvalidatingCache` around the given cache and
+// subscribes it to the bus. Closing the cache cancels the subscription.
+func NewInvalidatingCache(cache CacheInterface, bus InvalidationBus) (*InvalidatingCache, error) {
+	i, err := newInvalidator(bus, cache)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InvalidatingCache{CacheInterface: cache, invalidator: i}, nil
+}
+
+// The `Set` function stores an item in the local cache and invalidates it on the other instances.
+func (c *InvalidatingCache) Set(ctx context.Context, cacheKey string, item []byte) error {
+	return c.SetWithTTL(ctx, cacheKey, item, DefaultExpiration)
+}
+
+// The `SetWithTTL` function stores an item with its own TTL in the local cache and invalidates it on
+// the other instances.
+func (c *InvalidatingCache) SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error {
+	if err := c.CacheInterface.SetWithTTL(ctx, cacheKey, item, ttl); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: []string{cacheKey}})
+
+	return nil
+}
+
+// The `GetEntry` function retrieves an item from the local cache with the metadata stored by
+// `GetOrLoad`.
+func (c *InvalidatingCache) GetEntry(ctx context.Context, cacheKey string) (providers.Entry, bool, error) {
+	return getEntry(ctx, c.CacheInterface, cacheKey)
+}
+
+// The `SetEntry` function stores an item with the metadata used by `GetOrLoad` in the local cache and
+// invalidates it on the other instances.
+func (c *InvalidatingCache) SetEntry(ctx context.Context, cacheKey string, entry providers.Entry, ttl time.Duration) error {
+	if err := setEntry(ctx, c.CacheInterface, cacheKey, entry, ttl); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: []string{cacheKey}})
+
+	return nil
+}
+
+// The `SetMany` function stores multiple items in the local cache and invalidates them on the other
+// instances with a single event.
+func (c *InvalidatingCache) SetMany(ctx context.Context, items map[string][]byte) error {
+	if err := c.CacheInterface.SetMany(ctx, items); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: mapKeys(items)})
+
+	return nil
+}
+
+// The `Delete` function removes an item from the local cache and from the other instances.
+func (c *InvalidatingCache) Delete(ctx context.Context, cacheKey string) error {
+	if err := c.CacheInterface.Delete(ctx, cacheKey); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: []string{cacheKey}})
+
+	return nil
+}
+
+// The `DeleteMany` function removes multiple items from the local cache and from the other instances.
+func (c *InvalidatingCache) DeleteMany(ctx context.Context, cacheKeys []string) error {
+	if err := c.CacheInterface.DeleteMany(ctx, cacheKeys); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: cacheKeys})
+
+	return nil
+}
+
+// The `Clear` function removes all items from the local cache and from the other instances.
+func (c *InvalidatingCache) Clear(ctx context.Context) error {
+	if err := c.CacheInterface.Clear(ctx); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{All: true})
+
+	return nil
+}
+
+// The `ClearPrefix` function removes all items whose cache keys start with the given prefix from the
+// local cache and from the other instances. It returns `ErrClearPrefixUnsupported` if the local cache
+// does not implement `PrefixClearer`.
+func (c *InvalidatingCache) ClearPrefix(ctx context.Context, prefix string) error {
+	clearer, ok := c.CacheInterface.(PrefixClearer)
+	if !ok {
+		return ErrClearPrefixUnsupported
+	}
+
+	if err := clearer.ClearPrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Prefix: prefix, All: prefix == ""})
+
+	return nil
+}
+
+// The `Close` function cancels the subscription to the bus and closes the local cache.
+func (c *InvalidatingCache) Close(ctx context.Context) error {
+	c.invalidator.close()
+
+	return c.CacheInterface.Close(ctx)
+}
+
+// The `Lock` function acquires the distributed lock of the local cache used by `GetOrLoad`.
+func (c *InvalidatingCache) Lock(ctx context.Context, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	return lockThrough(ctx, c.CacheInterface, cacheKey, ttl)
+}
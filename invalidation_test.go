@@ -0,0 +1,148 @@
+package cachego
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"github.com/wasilak/cachego/config"
+)
+
+func TestLocalBus(t *testing.T) {
+	ctx := context.Background()
+	bus := NewLocalBus()
+
+	var first, second []InvalidationEvent
+
+	unsubscribe, err := bus.Subscribe(func(event InvalidationEvent) { first = append(first, event) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := bus.Subscribe(func(event InvalidationEvent) { second = append(second, event) }); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bus.Publish(ctx, InvalidationEvent{Keys: []string{"k"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	unsubscribe()
+
+	if err := bus.Publish(ctx, InvalidationEvent{All: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(first) != 1 || !slices.Equal(first[0].Keys, []string{"k"}) {
+		t.Errorf("events of the unsubscribed handler = %+v, want only the first event", first)
+	}
+
+	if len(second) != 2 || !second[1].All {
+		t.Errorf("events of the subscribed handler = %+v, want both events", second)
+	}
+}
+
+func TestInvalidatingCacheEvictsOtherInstances(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		write func(c *InvalidatingCache) error
+		want  map[string]bool
+	}{
+		{
+			name:  "Set",
+			write: func(c *InvalidatingCache) error { return c.Set(ctx, "user:1", []byte("new")) },
+			want:  map[string]bool{"user:1": false, "user:2": true, "order:1": true},
+		},
+		{
+			name: "SetMany",
+			write: func(c *InvalidatingCache) error {
+				return c.SetMany(ctx, map[string][]byte{"user:1": nil, "order:1": nil})
+			},
+			want: map[string]bool{"user:1": false, "user:2": true, "order:1": false},
+		},
+		{
+			name:  "Delete",
+			write: func(c *InvalidatingCache) error { return c.Delete(ctx, "user:2") },
+			want:  map[string]bool{"user:1": true, "user:2": false, "order:1": true},
+		},
+		{
+			name:  "DeleteMany",
+			write: func(c *InvalidatingCache) error { return c.DeleteMany(ctx, []string{"user:1", "user:2"}) },
+			want:  map[string]bool{"user:1": false, "user:2": false, "order:1": true},
+		},
+		{
+			name:  "ClearPrefix",
+			write: func(c *InvalidatingCache) error { return c.ClearPrefix(ctx, "user:") },
+			want:  map[string]bool{"user:1": false, "user:2": false, "order:1": true},
+		},
+		{
+			name:  "Clear",
+			write: func(c *InvalidatingCache) error { return c.Clear(ctx) },
+			want:  map[string]bool{"user:1": false, "user:2": false, "order:1": false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus := NewLocalBus()
+
+			writer, err := NewInvalidatingCache(newTestCache(t, config.Config{}), bus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer writer.Close(ctx)
+
+			local := newTestCache(t, config.Config{})
+
+			other, err := NewInvalidatingCache(local, bus)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer other.Close(ctx)
+
+			for key := range tt.want {
+				if err := local.Set(ctx, key, []byte("old")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := tt.write(writer); err != nil {
+				t.Fatal(err)
+			}
+
+			for key, want := range tt.want {
+				if found, err := other.Has(ctx, key); err != nil || found != want {
+					t.Errorf("Has(%q) on the other instance = %v, %v, want %v", key, found, err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidatingCacheKeepsOwnWrites(t *testing.T) {
+	ctx := context.Background()
+	bus := NewLocalBus()
+
+	c, err := NewInvalidatingCache(newTestCache(t, config.Config{}), bus)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set(ctx, "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+
+	if item, found, err := c.Get(ctx, "k"); err != nil || !found || string(item) != "v" {
+		t.Errorf("Get() = %q, %v, %v, want the item written through the cache", item, found, err)
+	}
+
+	if err := c.Close(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(bus.handlers); n != 0 {
+		t.Errorf("subscriptions after Close() = %d, want 0", n)
+	}
+}
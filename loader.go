@@ -0,0 +1,228 @@
+package cachego
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"time"
+
+	"github.com/wasilak/cachego/providers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"golang.org/x/sync/singleflight"
+)
+
+// The `LoaderFunc` type is a function loading an item from the source of truth when it is missing from
+// the cache.
+type LoaderFunc func(ctx context.Context) ([]byte, error)
+
+// The `locker` interface is implemented by providers supporting a distributed lock, such as redis. It
+// is used by `GetOrLoad` when `LoadLock` is enabled in the cache configuration.
+type locker interface {
+	Lock(ctx context.Context, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error)
+}
+
+// The `lockThrough` function acquires the distributed lock of a wrapped cache for decorators that
+// forward `GetOrLoad` locking. If the wrapped cache has no distributed lock, the lock is always
+// acquired locally.
+func lockThrough(ctx context.Context, cache CacheInterface, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	l, ok := cache.(locker)
+	if !ok {
+		return func(context.Context) error { return nil }, true, nil
+	}
+
+	return l.Lock(ctx, cacheKey, ttl)
+}
+
+// `loadGroup` deduplicates concurrent loads of the same key of the same cache within the process.
+var loadGroup singleflight.Group
+
+// `lockPollInterval` is how often `GetOrLoad` checks the cache while another instance holds the load
+// lock for a key.
+const lockPollInterval = 50 * time.Millisecond
+
+// The `GetOrLoad` function is a read-through helper on top of any `CacheInterface`. It returns the item
+// stored under the given key or, on a miss, calls the loader and caches its result with the configured
+// TTL. Concurrent calls for the same key share a single loader call, so an expired hot key doesn't
+// stampede the source of truth. When `LoadLock` is enabled and the provider supports it (redis), the
+// load is also deduplicated across instances with a distributed lock. Loader errors are returned to
+// all waiting callers and are never cached.
+//
+// With `StaleWhileRevalidate` enabled, an item past its TTL is returned immediately while a background
+// refresh runs. With `StaleIfError` enabled, an item past its TTL is returned when the refresh fails.
+// Whether a stale item was served is recorded in the `cache.stale` span attribute. Stale serving and
+// early expiration need a cache that stores the soft expiry with the item, as all built-in providers
+// and decorators do; other caches store loaded items with `Set` and never serve them stale.
+//
+// With `EarlyExpirationBeta` enabled, a fresh item may be reloaded before its TTL ends, with a
+// probability that grows as the expiry approaches (XFetch). The caller that draws the early expiration
+// reloads the item and gets the new value, or the current one if the loader fails; all others keep
+// getting the current item. Early reloads are recorded in the `cache.early_expiration` span attribute.
+func GetOrLoad(ctx context.Context, cache CacheInterface, cacheKey string, loader LoaderFunc) ([]byte, error) {
+	tracer := otel.Tracer("Cache")
+	ctx, span := tracer.Start(ctx, "GetOrLoad")
+	defer span.End()
+
+	entry, found, err := getEntry(ctx, cache, cacheKey)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := cache.GetConfig()
+	age, stale := entryStale(entry, time.Now())
+
+	switch {
+	case found && !stale && expiresEarly(entry, time.Now(), cfg.EarlyExpirationBeta):
+		span.SetAttributes(attribute.Bool("cache.early_expiration", true))
+
+		select {
+		case res := <-refresh(ctx, cache, cacheKey, loader):
+			if res.Err != nil {
+				slog.WarnContext(ctx, "failed to reload item before expiry", "key", cacheKey, slog.Any("message", res.Err))
+				return entry.Value, nil
+			}
+			return res.Val.([]byte), nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	case found && !stale:
+		return entry.Value, nil
+	case found && cfg.StaleWhileRevalidate > 0 && age <= cfg.StaleWhileRevalidate:
+		span.SetAttributes(attribute.Bool("cache.stale", true))
+
+		result := refresh(ctx, cache, cacheKey, loader)
+		go func() {
+			if res := <-result; res.Err != nil {
+				slog.WarnContext(ctx, "failed to refresh stale item", "key", cacheKey, slog.Any("message", res.Err))
+			}
+		}()
+
+		return entry.Value, nil
+	}
+
+	result := refresh(ctx, cache, cacheKey, loader)
+
+	select {
+	case res := <-result:
+		if res.Err != nil {
+			if found && cfg.StaleIfError > 0 && age <= cfg.StaleIfError {
+				span.SetAttributes(attribute.Bool("cache.stale", true))
+				slog.WarnContext(ctx, "serving stale item after failed refresh", "key", cacheKey, slog.Any("message", res.Err))
+				return entry.Value, nil
+			}
+			return nil, res.Err
+		}
+		return res.Val.([]byte), nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// The `refresh` function starts loading the item, or joins the load already running for it, and
+// returns the channel its result is delivered on. The load is shared by all callers waiting for the
+// key, so it must not be cancelled when the caller that started it goes away.
+func refresh(ctx context.Context, cache CacheInterface, cacheKey string, loader LoaderFunc) <-chan singleflight.Result {
+	groupKey := cacheIdentity(cache) + ":" + cacheKey
+
+	return loadGroup.DoChan(groupKey, func() (any, error) {
+		return load(context.WithoutCancel(ctx), cache, cacheKey, loader)
+	})
+}
+
+// The `cacheIdentity` function returns the key identifying a cache in `loadGroup`. Caches held by
+// pointer, like all built-in providers and decorators, are identified by their type and address.
+// Other implementations are identified by their type and namespace, as they have no address to tell
+// their instances apart.
+func cacheIdentity(cache CacheInterface) string {
+	v := reflect.ValueOf(cache)
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		return fmt.Sprintf("%T@%x", cache, v.Pointer())
+	}
+
+	return fmt.Sprintf("%T/%s", cache, cache.GetConfig().Namespace)
+}
+
+// The `load` function calls the loader and stores its result in the cache. When a distributed lock is
+// enabled and held by another instance, it waits for that instance to fill the cache instead and only
+// falls back to calling the loader if the lock TTL passes without a result.
+func load(ctx context.Context, cache CacheInterface, cacheKey string, loader LoaderFunc) ([]byte, error) {
+	cfg := cache.GetConfig()
+
+	if l, ok := cache.(locker); ok && cfg.LoadLock {
+		unlock, acquired, err := l.Lock(ctx, cacheKey, cfg.LoadLockTTL)
+		if err != nil {
+			return nil, err
+		}
+
+		if acquired {
+			defer unlock(context.WithoutCancel(ctx))
+		} else {
+			item, found, err := waitForItem(ctx, cache, cacheKey, cfg.LoadLockTTL)
+			if err != nil || found {
+				return item, err
+			}
+		}
+	}
+
+	start := time.Now()
+
+	item, err := loader(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := storeLoaded(ctx, cache, cacheKey, item, time.Since(start)); err != nil {
+		slog.WarnContext(ctx, "failed to cache loaded item", "key", cacheKey, slog.Any("message", err))
+	}
+
+	return item, nil
+}
+
+// The `storeLoaded` function stores a loaded item with the configured TTL. With stale serving or early
+// expiration enabled, the item is stored as an entry with its soft expiry and load duration, and kept
+// for the stale window on top of the TTL.
+func storeLoaded(ctx context.Context, cache CacheInterface, cacheKey string, item []byte, loadDuration time.Duration) error {
+	cfg := cache.GetConfig()
+
+	if !useEnvelope(cfg) || cfg.TTL <= 0 {
+		return cache.Set(ctx, cacheKey, item)
+	}
+
+	entry := providers.Entry{
+		Value:        item,
+		SoftExpiry:   time.Now().Add(cfg.TTL),
+		LoadDuration: loadDuration,
+	}
+
+	return setEntry(ctx, cache, cacheKey, entry, cfg.TTL+staleWindow(cfg))
+}
+
+// The `waitForItem` function polls the cache until a fresh item appears, the timeout passes or the
+// context is done.
+func waitForItem(ctx context.Context, cache CacheInterface, cacheKey string, timeout time.Duration) ([]byte, bool, error) {
+	ticker := time.NewTicker(lockPollInterval)
+	defer ticker.Stop()
+
+	deadline := time.After(timeout)
+
+	for {
+		select {
+		case <-ticker.C:
+			entry, found, err := getEntry(ctx, cache, cacheKey)
+			if err != nil {
+				return nil, false, err
+			}
+
+			if _, stale := entryStale(entry, time.Now()); found && !stale {
+				return entry.Value, true, nil
+			}
+		case <-deadline:
+			return nil, false, nil
+		case <-ctx.Done():
+			return nil, false, ctx.Err()
+		}
+	}
+}
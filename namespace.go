@@ -0,0 +1,178 @@
+package cachego
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"github.com/wasilak/cachego/providers"
+)
+
+// The `PrefixClearer` interface is implemented by caches that can remove all items whose cache keys
+// start with a prefix, e.g. with `SCAN` and `UNLINK` on Redis or `DropPrefix` on Badger. All built-in
+// providers implement it. The prefix is relative to the `Namespace` of the cache.
+type PrefixClearer interface {
+	ClearPrefix(ctx context.Context, prefix string) error
+}
+
+// The `NamespacedCache` type is a view of an existing cache that stores its items under a sub-namespace,
+// e.g. one view per tenant on top of a single Redis cache. The keys of the view are prefixed with the
+// namespace and `config.NamespaceSeparator` before they reach the underlying cache, on top of any
+// `Namespace` configured on the cache itself. `Clear` only removes the items of the view and `Close` is
+// a no-op, as the underlying cache is owned by whoever created it.
+type NamespacedCache struct {
+	CacheInterface
+	namespace string
+	prefix    string
+}
+
+// The `NewNamespacedCache` function creates a view of the given cache with the given sub-namespace.
+// Views of views are flattened, so `NewNamespacedCache(NewNamespacedCache(cache, "a"), "b")` stores
+// its items under `a:b:` in `cache`.
+func NewNamespacedCache(cache CacheInterface, namespace string) *NamespacedCache {
+	if parent, ok := cache.(*NamespacedCache); ok {
+		cache = parent.CacheInterface
+		namespace = parent.namespace + config.NamespaceSeparator + namespace
+	}
+
+	return &NamespacedCache{
+		CacheInterface: cache,
+		namespace:      namespace,
+		prefix:         namespace + config.NamespaceSeparator,
+	}
+}
+
+// The `key` function returns the key of an item in the underlying cache.
+func (c *NamespacedCache) key(cacheKey string) string {
+	return c.prefix + cacheKey
+}
+
+// The `keys` function returns the keys of multiple items in the underlying cache, in the same order.
+func (c *NamespacedCache) keys(cacheKeys []string) []string {
+	keys := make([]string, len(cacheKeys))
+	for i, cacheKey := range cacheKeys {
+		keys[i] = c.key(cacheKey)
+	}
+
+	return keys
+}
+
+// The `Init` function is a no-op, the underlying cache is initialized by whoever created it.
+func (c *NamespacedCache) Init(ctx context.Context) error {
+	return nil
+}
+
+// The `GetConfig` function returns the configuration of the underlying cache with the sub-namespace of
+// the view appended to its `Namespace`.
+func (c *NamespacedCache) GetConfig() config.Config {
+	cfg := c.CacheInterface.GetConfig()
+
+	if cfg.Namespace == "" {
+		cfg.Namespace = c.namespace
+	} else {
+		cfg.Namespace += config.NamespaceSeparator + c.namespace
+	}
+
+	return cfg
+}
+
+func (c *NamespacedCache) Get(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	return c.CacheInterface.Get(ctx, c.key(cacheKey))
+}
+
+func (c *NamespacedCache) Set(ctx context.Context, cacheKey string, item []byte) error {
+	return c.CacheInterface.Set(ctx, c.key(cacheKey), item)
+}
+
+func (c *NamespacedCache) SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error {
+	return c.CacheInterface.SetWithTTL(ctx, c.key(cacheKey), item, ttl)
+}
+
+func (c *NamespacedCache) GetItemTTL(ctx context.Context, cacheKey string) (time.Duration, bool, error) {
+	return c.CacheInterface.GetItemTTL(ctx, c.key(cacheKey))
+}
+
+func (c *NamespacedCache) ExtendTTL(ctx context.Context, cacheKey string, by time.Duration) error {
+	return c.CacheInterface.ExtendTTL(ctx, c.key(cacheKey), by)
+}
+
+func (c *NamespacedCache) Touch(ctx context.Context, cacheKey string, ttl time.Duration) error {
+	return c.CacheInterface.Touch(ctx, c.key(cacheKey), ttl)
+}
+
+func (c *NamespacedCache) Delete(ctx context.Context, cacheKey string) error {
+	return c.CacheInterface.Delete(ctx, c.key(cacheKey))
+}
+
+func (c *NamespacedCache) DeleteMany(ctx context.Context, cacheKeys []string) error {
+	return c.CacheInterface.DeleteMany(ctx, c.keys(cacheKeys))
+}
+
+func (c *NamespacedCache) Has(ctx context.Context, cacheKey string) (bool, error) {
+	return c.CacheInterface.Has(ctx, c.key(cacheKey))
+}
+
+// The `GetMany` function retrieves multiple items from the underlying cache. The returned map is keyed
+// by the cache keys of the view, without the namespace.
+func (c *NamespacedCache) GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error) {
+	found, err := c.CacheInterface.GetMany(ctx, c.keys(cacheKeys))
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[string][]byte, len(found))
+	for key, item := range found {
+		items[strings.TrimPrefix(key, c.prefix)] = item
+	}
+
+	return items, nil
+}
+
+func (c *NamespacedCache) SetMany(ctx context.Context, items map[string][]byte) error {
+	prefixed := make(map[string][]byte, len(items))
+	for cacheKey, item := range items {
+		prefixed[c.key(cacheKey)] = item
+	}
+
+	return c.CacheInterface.SetMany(ctx, prefixed)
+}
+
+// The `Clear` function removes all items of the view, leaving the rest of the underlying cache
+// untouched. It returns `ErrClearPrefixUnsupported` if the underlying cache does not implement
+// `PrefixClearer`.
+func (c *NamespacedCache) Clear(ctx context.Context) error {
+	return c.ClearPrefix(ctx, "")
+}
+
+// The `ClearPrefix` function removes all items of the view whose cache keys start with the given
+// prefix.
+func (c *NamespacedCache) ClearPrefix(ctx context.Context, prefix string) error {
+	clearer, ok := c.CacheInterface.(PrefixClearer)
+	if !ok {
+		return ErrClearPrefixUnsupported
+	}
+
+	return clearer.ClearPrefix(ctx, c.key(prefix))
+}
+
+// The `Close` function is a no-op, the underlying cache is closed by whoever created it.
+func (c *NamespacedCache) Close(ctx context.Context) error {
+	return nil
+}
+
+// The `Lock` function acquires the distributed lock used by `GetOrLoad` for the key in the view's
+// namespace. If the underlying cache has no distributed lock, the lock is always acquired locally.
+func (c *NamespacedCache) Lock(ctx context.Context, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	return lockThrough(ctx, c.CacheInterface, c.key(cacheKey), ttl)
+}
+
+// The `GetEntry` function retrieves an item of the view with the metadata stored by `GetOrLoad`.
+func (c *NamespacedCache) GetEntry(ctx context.Context, cacheKey string) (providers.Entry, bool, error) {
+	return getEntry(ctx, c.CacheInterface, c.key(cacheKey))
+}
+
+// The `SetEntry` function stores an item of the view with the metadata used by `GetOrLoad`.
+func (c *NamespacedCache) SetEntry(ctx context.Context, cacheKey string, entry providers.Entry, ttl time.Duration) error {
+	return setEntry(ctx, c.CacheInterface, c.key(cacheKey), entry, ttl)
+}
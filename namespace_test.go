@@ -0,0 +1,107 @@
+package cachego
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/wasilak/cachego/config"
+)
+
+func TestNamespacedCacheIsolation(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestCache(t, config.Config{Namespace: "app"})
+
+	a := NewNamespacedCache(cache, "a")
+	b := NewNamespacedCache(cache, "b")
+
+	for view, item := range map[*NamespacedCache]string{a: "from a", b: "from b"} {
+		if err := view.Set(ctx, "k", []byte(item)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := a.SetMany(ctx, map[string][]byte{"x": []byte("1")}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		view CacheInterface
+		key  string
+		want string
+	}{
+		{name: "view a", view: a, key: "k", want: "from a"},
+		{name: "view b", view: b, key: "k", want: "from b"},
+		{name: "underlying cache", view: cache, key: "a:k", want: "from a"},
+		{name: "nested view", view: NewNamespacedCache(NewNamespacedCache(cache, "b"), "c"), key: "k", want: ""},
+		{name: "unprefixed key", view: cache, key: "k", want: ""},
+		{name: "SetMany of another view", view: b, key: "x", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item, found, err := tt.view.Get(ctx, tt.key)
+			if err != nil || found != (tt.want != "") || string(item) != tt.want {
+				t.Errorf("Get(%q) = %q, %v, %v, want %q", tt.key, item, found, err, tt.want)
+			}
+		})
+	}
+
+	items, err := a.GetMany(ctx, []string{"k", "x"})
+	if err != nil || !slices.Equal(slices.Sorted(maps.Keys(items)), []string{"k", "x"}) {
+		t.Errorf("GetMany() = %q, %v, want the keys of the view without the namespace", items, err)
+	}
+
+	if cfg := a.GetConfig(); cfg.Namespace != "app:a" {
+		t.Errorf("GetConfig().Namespace = %q, want %q", cfg.Namespace, "app:a")
+	}
+
+	if nested := NewNamespacedCache(a, "c"); nested.key("k") != "a:c:k" {
+		t.Errorf("nested view key = %q, want %q", nested.key("k"), "a:c:k")
+	}
+}
+
+func TestNamespacedCacheClearPrefix(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		clear func(view *NamespacedCache) error
+		want  map[string]bool
+	}{
+		{
+			name:  "ClearPrefix",
+			clear: func(view *NamespacedCache) error { return view.ClearPrefix(ctx, "user:") },
+			want:  map[string]bool{"a:user:1": false, "a:user:2": false, "a:order:1": true, "b:user:1": true, "user:1": true},
+		},
+		{
+			name:  "Clear",
+			clear: func(view *NamespacedCache) error { return view.Clear(ctx) },
+			want:  map[string]bool{"a:user:1": false, "a:user:2": false, "a:order:1": false, "b:user:1": true, "user:1": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newTestCache(t, config.Config{})
+
+			for key := range tt.want {
+				if err := cache.Set(ctx, key, []byte("v")); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := tt.clear(NewNamespacedCache(cache, "a")); err != nil {
+				t.Fatal(err)
+			}
+
+			for key, want := range tt.want {
+				if found, err := cache.Has(ctx, key); err != nil || found != want {
+					t.Errorf("Has(%q) = %v, %v, want %v", key, found, err, want)
+				}
+			}
+		})
+	}
+}
@@ -1,11 +1,13 @@
 package providers
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	badger "github.com/dgraph-io/badger/v4"
+	"github.com/dgraph-io/badger/v4/options"
 	"github.com/wasilak/cachego/config"
 )
 
@@ -24,10 +26,18 @@ import (
 // boundaries.
 // @property {string} Path - The `Path` property is a string that represents the file path where the
 // BadgerCache database is stored.
+// @property sizeJanitor - The `sizeJanitor` property runs the value log GC when the value log grows
+// past `BadgerGCSizeThreshold`, between the regular maintenance runs.
+// @property legacySweepDone - The `legacySweepDone` property is set by the maintenance loop once the
+// database holds no items of the legacy two-key layout, so it stops sweeping for them.
 type BadgerCache struct {
-	Cache  *badger.DB
-	Path   string
-	Config config.Config
+	Cache           *badger.DB
+	Path            string
+	Config          config.Config
+	janitor         *janitor
+	sizeJanitor     *janitor
+	telemetry       *telemetry
+	legacySweepDone bool
 }
 
 func (c *BadgerCache) GetConfig() config.Config {
@@ -35,190 +45,597 @@ func (c *BadgerCache) GetConfig() config.Config {
 }
 
 // The `Init` function is used to initialize the BadgerCache. It opens a connection to the Badger
-// database using the provided path and sets the Cache field of the BadgerCache struct to the opened
-// database. If any error occurs during the initialization process, it is returned.
-func (c *BadgerCache) Init() error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Init")
-	defer span.End()
+// database using the provided path and options from the cache configuration and sets the Cache field of
+// the BadgerCache struct to the opened database. It also starts the background maintenance loop
+// configured with `BadgerGCInterval`, and the value log size check if `BadgerGCSizeThreshold` is set,
+// unless the database is in-memory or read-only. The size of the
+// LSM tree and value log is reported by the `cache.size` gauge. If any error occurs during the
+// initialization process, it is returned.
+func (c *BadgerCache) Init(ctx context.Context) error {
+	telemetry, err := newTelemetry(c.Config)
+	if err != nil {
+		return err
+	}
 
-	opts := badger.DefaultOptions(c.Path)
-	opts.Logger = nil
+	c.telemetry = telemetry
+
+	_, op := c.telemetry.start(ctx, "Init")
+	defer op.end()
+
+	opts, err := c.options()
+	if err != nil {
+		return op.fail(err)
+	}
 
 	db, err := badger.Open(opts)
+	if errors.Is(err, badger.ErrEncryptionKeyMismatch) {
+		return op.fail(fmt.Errorf("badger cache at %q was created with a different encryption key (or without one): %w", c.Path, err))
+	}
 	if err != nil {
-		return err
+		return op.fail(err)
 	}
 
 	c.Cache = db
 
-	return nil
+	if !c.Config.BadgerInMemory && !c.Config.BadgerReadOnly {
+		c.janitor = startJanitor(c.Config.BadgerGCInterval, c.runMaintenance)
+
+		if c.Config.BadgerGCInterval > 0 && c.Config.BadgerGCSizeThreshold > 0 {
+			c.sizeJanitor = startJanitor(c.Config.BadgerGCSizeCheckInterval, c.checkValueLogSize)
+		}
+	}
+
+	return op.fail(c.telemetry.registerGauges(c.Config, nil, c.size))
 }
 
-// The `Get` function is used to retrieve an item from the cache based on a given cache key. It takes a
-// cache key as input and returns three values: the content of the item (as an interface{}), a boolean
-// indicating if the item exists in the cache, and an error if any occurred.
-func (c *BadgerCache) Get(cacheKey string) ([]byte, bool, error) {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Get")
-	defer span.End()
+// The `size` function returns the size in bytes of the LSM tree and value log of the database.
+func (c *BadgerCache) size(context.Context) (int64, error) {
+	lsm, vlog := c.Cache.Size()
+
+	return lsm + vlog, nil
+}
+
+// The `options` function builds the Badger options from the cache configuration. Zero values keep the
+// Badger defaults.
+func (c *BadgerCache) options() (badger.Options, error) {
+	opts := badger.DefaultOptions(c.Path)
+	opts.Logger = nil
 
-	item, ttl, err := c.retrieveFromCache(cacheKey)
+	if c.Config.BadgerInMemory {
+		opts = opts.WithDir("").WithValueDir("").WithInMemory(true)
+	}
+
+	opts = opts.WithReadOnly(c.Config.BadgerReadOnly).WithSyncWrites(c.Config.BadgerSyncWrites)
+
+	switch c.Config.BadgerCompression {
+	case "":
+	case "none":
+		opts = opts.WithCompression(options.None)
+	case "snappy":
+		opts = opts.WithCompression(options.Snappy)
+	case "zstd":
+		opts = opts.WithCompression(options.ZSTD)
+	default:
+		return opts, fmt.Errorf("unknown badger compression %q", c.Config.BadgerCompression)
+	}
+
+	if c.Config.BadgerZSTDLevel != 0 {
+		opts = opts.WithZSTDCompressionLevel(c.Config.BadgerZSTDLevel)
+	}
+
+	if c.Config.BadgerBlockCacheSize != 0 {
+		opts = opts.WithBlockCacheSize(c.Config.BadgerBlockCacheSize)
+	}
+
+	if c.Config.BadgerIndexCacheSize != 0 {
+		opts = opts.WithIndexCacheSize(c.Config.BadgerIndexCacheSize)
+	}
+
+	if c.Config.BadgerValueThreshold != 0 {
+		opts = opts.WithValueThreshold(c.Config.BadgerValueThreshold)
+	}
+
+	if c.Config.BadgerMemTableSize != 0 {
+		opts = opts.WithMemTableSize(c.Config.BadgerMemTableSize)
+	}
+
+	key, err := c.encryptionKey()
 	if err != nil {
-		return item, false, err
+		return opts, err
 	}
 
-	now := time.Now()
+	if len(key) > 0 {
+		opts = opts.WithEncryptionKey(key)
 
-	if ttl.Unix() <= now.Unix() {
-		c.delete(cacheKey)
-		return item, false, nil
+		// Badger recommends an index cache when encryption is enabled, otherwise every table index is
+		// decrypted on each read
+		if c.Config.BadgerIndexCacheSize == 0 {
+			opts = opts.WithIndexCacheSize(defaultEncryptedIndexCacheSize)
+		}
+
+		if c.Config.BadgerEncryptionKeyRotation != 0 {
+			opts = opts.WithEncryptionKeyRotationDuration(c.Config.BadgerEncryptionKeyRotation)
+		}
 	}
 
-	return item, true, nil
+	return opts, nil
 }
 
-// The `Set` function is used to store an item in the cache. It takes a cache key and an item as input.
-// The function serializes the item into bytes using JSON encoding and creates a `BadgerItem` struct
-// with the serialized item and a TTL (time to live) value. It then starts a transaction, sets the
-// cache key-value pair in the transaction, and commits the transaction to persist the changes in the
-// cache. If any error occurs during the process, it is returned.
-func (c *BadgerCache) Set(cacheKey string, item []byte) error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Set")
-	defer span.End()
+// `badgerEntryMeta` is the user metadata byte set on every entry written by the BadgerCache. It tells
+// the entries apart from keys of the legacy two-key layout, so user keys can never collide with legacy
+// `_content` and `_ttl` keys.
+const badgerEntryMeta byte = 1
+
+// The `badgerItem` type represents an item read from the Badger database.
+// @property value - The `value` property is the content of the item.
+// @property expiresAt - The `expiresAt` property is the expiry time of the item, a zero time means the
+// item never expires.
+// @property legacy - The `legacy` property is true if the item is stored in the legacy two-key layout.
+type badgerItem struct {
+	value     []byte
+	expiresAt time.Time
+	legacy    bool
+}
 
-	// Serialize the ttl to bytes
-	ttl := time.Now().Add(c.Config.TTL)
-	ttlBytes, err := json.Marshal(ttl)
-	if err != nil {
-		return err
+// The `entry` function unwraps the item. Legacy items were stored before envelopes existed, so their
+// value is the item itself.
+func (i badgerItem) entry() (Entry, error) {
+	if i.legacy {
+		return Entry{Value: i.value}, nil
 	}
 
-	// Start a transaction
-	txn := c.Cache.NewTransaction(true)
-	defer txn.Discard()
+	return decodeEntry(i.value)
+}
 
-	// Set the cache key-value pair
-	if err := txn.Set([]byte(fmt.Sprintf("%s_content", cacheKey)), item); err != nil {
-		return err
+// The `stored` function returns the value to store when the item is rewritten as a single entry.
+func (i badgerItem) stored() []byte {
+	if i.legacy {
+		return encodeValue(i.value)
 	}
 
-	// Set the cache key-value pair
-	if err := txn.Set([]byte(fmt.Sprintf("%s_ttl", cacheKey)), ttlBytes); err != nil {
-		return err
+	return i.value
+}
+
+// The `ttl` function returns the remaining time to live of the item, or `config.NoExpiration`.
+func (i badgerItem) ttl() time.Duration {
+	if i.expiresAt.IsZero() {
+		return config.NoExpiration
 	}
 
-	// Commit the transaction
-	if err := txn.Commit(); err != nil {
+	return time.Until(i.expiresAt)
+}
+
+// The `Get` function is used to retrieve an item from the cache based on a given cache key. It takes a
+// cache key as input and returns three values: the content of the item (as an interface{}), a boolean
+// indicating if the item exists in the cache, and an error if any occurred. Items still stored in the
+// legacy two-key layout are migrated to a single entry on read.
+func (c *BadgerCache) Get(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	_, op := c.telemetry.start(ctx, "Get")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	entry, found, err := c.getEntry(op, cacheKey)
+
+	return entry.Value, found, err
+}
+
+// The `GetEntry` function is used to retrieve an item together with the soft expiry and load duration
+// it was stored with by `SetEntry`. It is used by `GetOrLoad`.
+func (c *BadgerCache) GetEntry(ctx context.Context, cacheKey string) (Entry, bool, error) {
+	_, op := c.telemetry.start(ctx, "GetEntry")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	return c.getEntry(op, cacheKey)
+}
+
+// The `SetEntry` function is used to store an item in an envelope with its soft expiry and load
+// duration, with the given TTL as its hard expiry. `Get` returns the item without the envelope. It is
+// used by `GetOrLoad`.
+func (c *BadgerCache) SetEntry(ctx context.Context, cacheKey string, entry Entry, ttl time.Duration) error {
+	_, op := c.telemetry.start(ctx, "SetEntry")
+	defer op.end()
+
+	return c.setWithTTL(op, cacheKey, encodeEntry(entry), ttl)
+}
+
+// The `getEntry` function reads and unwraps an item, migrating it if it is stored in the legacy layout.
+// Items with an invalid envelope are reported as missing.
+func (c *BadgerCache) getEntry(op *operation, cacheKey string) (Entry, bool, error) {
+	key := namespacedKey(c.Config, cacheKey)
+
+	var item badgerItem
+	var found bool
+
+	err := c.Cache.View(func(txn *badger.Txn) error {
+		var err error
+		item, found, err = readItem(txn, key)
 		return err
+	})
+	if err != nil {
+		return Entry{}, false, op.fail(err)
+	}
+
+	var entry Entry
+
+	if found {
+		if item.legacy {
+			item, found = c.migrateLegacyItem(key, item)
+		}
+
+		entry, err = item.entry()
+		found = found && err == nil
+	}
+
+	op.hit(found)
+
+	if !found {
+		op.lookup(1, 0)
+		return Entry{}, false, nil
 	}
 
-	return nil
+	op.lookup(1, 1)
+	op.size(len(item.value))
+
+	return entry, true, nil
+}
+
+// The `Set` function is used to store an item in the cache. It takes a cache key and an item as input
+// and stores the item with the TTL from the cache configuration.
+func (c *BadgerCache) Set(ctx context.Context, cacheKey string, item []byte) error {
+	_, op := c.telemetry.start(ctx, "Set")
+	defer op.end()
+
+	return c.setWithTTL(op, cacheKey, encodeValue(item), config.DefaultExpiration)
+}
+
+// The `SetWithTTL` function is used to store an item in the cache with its own time-to-live (TTL)
+// duration. The item is stored as a single Badger entry with a native TTL, so expired data is reclaimed
+// by Badger itself. Badger stores the expiry with a precision of one second, so it is rounded up to the
+// next second.
+func (c *BadgerCache) SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error {
+	_, op := c.telemetry.start(ctx, "SetWithTTL")
+	defer op.end()
+
+	return c.setWithTTL(op, cacheKey, encodeValue(item), ttl)
+}
+
+// The `setWithTTL` function stores an item, already in its stored form, with its own TTL as a single
+// Badger entry. It is shared by the write methods, so each of them records its telemetry once.
+func (c *BadgerCache) setWithTTL(op *operation, cacheKey string, item []byte, ttl time.Duration) error {
+	op.key(cacheKey)
+	op.size(len(item))
+
+	return op.fail(c.Cache.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(newBadgerEntry(namespacedKey(c.Config, cacheKey), item, resolveTTL(ttl, c.Config)))
+	}))
 }
 
 // The `GetItemTTL` function is used to retrieve the remaining time to live (TTL) of an item in the
 // cache. It takes a cache key as input and returns the remaining TTL duration, a boolean indicating if
-// the item exists in the cache, and an error if any occurred.
-func (c *BadgerCache) GetItemTTL(cacheKey string) (time.Duration, bool, error) {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "GetItemTTL")
-	defer span.End()
+// the item exists in the cache, and an error if any occurred. Items stored without an expiry report
+// `config.NoExpiration`, and items stored with `SetEntry` report the time left until their soft
+// expiry.
+func (c *BadgerCache) GetItemTTL(ctx context.Context, cacheKey string) (time.Duration, bool, error) {
+	_, op := c.telemetry.start(ctx, "GetItemTTL")
+	defer op.end()
 
-	var difference time.Duration
+	op.key(cacheKey)
 
-	_, ttl, err := c.retrieveFromCache(cacheKey)
-	if err != nil {
-		return difference, false, err
+	var item badgerItem
+	var found bool
+
+	err := c.Cache.View(func(txn *badger.Txn) error {
+		var err error
+		item, found, err = readItem(txn, namespacedKey(c.Config, cacheKey))
+		return err
+	})
+	if err != nil || !found {
+		return 0, false, op.fail(err)
 	}
 
-	now := time.Now()
-	difference = ttl.Sub(now)
+	if item.legacy {
+		return item.ttl(), true, nil
+	}
 
-	return difference, true, nil
+	return entryTTL(item.ttl(), item.value, time.Now()), true, nil
 }
 
-// The `ExtendTTL` function is used to extend the time to live (TTL) of an item in the cache. It takes
-// a cache key and an item as input. The function calls the `Set` function to update the item in the
-// cache with a new TTL. This effectively extends the lifespan of the item in the cache.
-func (c *BadgerCache) ExtendTTL(cacheKey string, item []byte) error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "ExtendTTL")
-	defer span.End()
+// The `ExtendTTL` function is used to extend the time to live (TTL) of an item in the cache by the
+// given duration. Badger can't change the TTL of an entry in place, so the entry is rewritten with its
+// current content. Extending a missing item is a no-op.
+func (c *BadgerCache) ExtendTTL(ctx context.Context, cacheKey string, by time.Duration) error {
+	_, op := c.telemetry.start(ctx, "ExtendTTL")
+	defer op.end()
 
-	c.Set(cacheKey, item)
+	op.key(cacheKey)
 
-	return nil
+	return op.fail(c.updateTTL(namespacedKey(c.Config, cacheKey), func(item badgerItem) time.Duration {
+		return extendTTL(item.ttl(), by)
+	}))
 }
 
-// The `retrieveFromCache` function is used to retrieve an item from the cache based on a given cache
-// key. It takes a cache key as input and returns a `BadgerItem` struct and an error.
-func (c *BadgerCache) retrieveFromCache(cacheKey string) ([]byte, time.Time, error) {
-	txn := c.Cache.NewTransaction(false)
-	defer txn.Discard()
+// The `Touch` function is used to reset the time to live (TTL) of an item in the cache to the given
+// duration, counted from now. The entry is rewritten with its current content. Touching a missing item
+// is a no-op.
+func (c *BadgerCache) Touch(ctx context.Context, cacheKey string, ttl time.Duration) error {
+	_, op := c.telemetry.start(ctx, "Touch")
+	defer op.end()
 
-	var itemValue []byte
-	var ttl time.Time
+	op.key(cacheKey)
 
-	itemTTL, err := txn.Get([]byte(fmt.Sprintf("%s_ttl", cacheKey)))
-	if err != nil {
-		if err == badger.ErrKeyNotFound {
-			return itemValue, ttl, nil
-		}
-		return itemValue, ttl, err
-	}
+	return op.fail(c.updateTTL(namespacedKey(c.Config, cacheKey), func(badgerItem) time.Duration {
+		return resolveTTL(ttl, c.Config)
+	}))
+}
 
-	err = itemTTL.Value(func(val []byte) error {
-		// Deserialize the value into the appropriate type
-		if err := json.Unmarshal(val, &ttl); err != nil {
+// The `updateTTL` function rewrites an existing item with the TTL returned by `newTTL` in a single
+// transaction. Legacy items are migrated to a single entry on the way.
+func (c *BadgerCache) updateTTL(cacheKey string, newTTL func(badgerItem) time.Duration) error {
+	return c.Cache.Update(func(txn *badger.Txn) error {
+		item, found, err := readItem(txn, cacheKey)
+		if err != nil || !found {
 			return err
 		}
-		return nil
+
+		if item.legacy {
+			if err := deleteLegacyItem(txn, cacheKey); err != nil {
+				return err
+			}
+		}
+
+		return txn.SetEntry(newBadgerEntry(cacheKey, item.stored(), newTTL(item)))
 	})
+}
 
-	if err != nil {
-		return itemValue, ttl, err
+// The `newBadgerEntry` function creates a Badger entry for an item with the given TTL.
+// `config.NoExpiration` creates an entry that never expires.
+func newBadgerEntry(cacheKey string, item []byte, ttl time.Duration) *badger.Entry {
+	entry := badger.NewEntry([]byte(cacheKey), item).WithMeta(badgerEntryMeta)
+
+	if ttl != config.NoExpiration {
+		entry.ExpiresAt = badgerExpiresAt(time.Now(), ttl)
+	}
+
+	return entry
+}
+
+// The `badgerExpiresAt` function returns the Badger expiry of an item stored at `now` with the given
+// TTL. Badger stores the expiry in whole seconds and treats an entry as expired once the current second
+// reaches it, so the expiry is rounded up to the next second: the item lives up to a second longer than
+// its TTL instead of up to a second shorter (e.g. not at all for TTLs below a second).
+func badgerExpiresAt(now time.Time, ttl time.Duration) uint64 {
+	expiresAt := now.Add(ttl)
+
+	seconds := expiresAt.Unix()
+	if expiresAt.After(time.Unix(seconds, 0)) {
+		seconds++
+	}
+
+	return uint64(seconds)
+}
+
+// The `readItem` function reads an item within the provided transaction. Expired entries are hidden by
+// Badger itself. If there is no entry for the key, the legacy two-key layout is checked.
+func readItem(txn *badger.Txn, cacheKey string) (badgerItem, bool, error) {
+	var item badgerItem
+
+	entry, err := txn.Get([]byte(cacheKey))
+	switch {
+	case err == badger.ErrKeyNotFound:
+		return readLegacyItem(txn, cacheKey)
+	case err != nil:
+		return item, false, err
+	case entry.UserMeta() != badgerEntryMeta:
+		// A key of the legacy layout, not an item
+		return item, false, nil
 	}
 
-	itemContent, err := txn.Get([]byte(fmt.Sprintf("%s_content", cacheKey)))
+	item.value, err = entry.ValueCopy(nil)
 	if err != nil {
-		if err == badger.ErrKeyNotFound {
-			return itemValue, ttl, err
-		}
-		return itemValue, ttl, err
+		return item, false, err
 	}
 
-	err = itemContent.Value(func(val []byte) error {
-		itemValue = val
+	if expiresAt := entry.ExpiresAt(); expiresAt > 0 {
+		item.expiresAt = time.Unix(int64(expiresAt), 0)
+	}
+
+	return item, true, nil
+}
+
+// The `Delete` function is used to delete an item from the cache based on a given cache key and returns
+// an error if any occurred.
+func (c *BadgerCache) Delete(ctx context.Context, cacheKey string) error {
+	_, op := c.telemetry.start(ctx, "Delete")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	return op.fail(c.deleteMany([]string{cacheKey}))
+}
+
+// The `DeleteMany` function is used to delete multiple items from the cache in a single transaction.
+// Keys of items still stored in the legacy two-key layout are removed as well.
+func (c *BadgerCache) DeleteMany(ctx context.Context, cacheKeys []string) error {
+	_, op := c.telemetry.start(ctx, "DeleteMany")
+	defer op.end()
+
+	op.keys(len(cacheKeys))
+
+	return op.fail(c.deleteMany(cacheKeys))
+}
+
+// The `deleteMany` function deletes the entries and legacy keys of multiple items in a single
+// transaction.
+func (c *BadgerCache) deleteMany(cacheKeys []string) error {
+	return c.Cache.Update(func(txn *badger.Txn) error {
+		for _, key := range namespacedKeys(c.Config, cacheKeys) {
+			if err := deleteWithMeta(txn, key, badgerEntryMeta); err != nil {
+				return err
+			}
+
+			if err := deleteLegacyItem(txn, key); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	})
+}
 
-	if err != nil {
-		return itemValue, ttl, err
+// The `deleteWithMeta` function deletes a key within the provided transaction only if it exists and
+// carries the given user metadata byte.
+func deleteWithMeta(txn *badger.Txn, key string, meta byte) error {
+	entry, err := txn.Get([]byte(key))
+	switch {
+	case err == badger.ErrKeyNotFound:
+		return nil
+	case err != nil:
+		return err
+	case entry.UserMeta() != meta:
+		return nil
 	}
 
-	return itemValue, ttl, nil
+	return txn.Delete([]byte(key))
 }
 
-// The `delete` function is used to delete an item from the cache based on a given cache key. It takes
-// a cache key as input and returns an error if any occurred.
-func (c *BadgerCache) delete(cacheKey string) error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Delete")
-	defer span.End()
+// The `Has` function is used to check if an item exists in the cache and has not expired yet, without
+// returning its content. Legacy items are reported but not migrated.
+func (c *BadgerCache) Has(ctx context.Context, cacheKey string) (bool, error) {
+	_, op := c.telemetry.start(ctx, "Has")
+	defer op.end()
 
-	// Start a transaction
-	txn := c.Cache.NewTransaction(true)
-	defer txn.Discard()
+	op.key(cacheKey)
 
-	// Delete the item by key
-	if err := txn.Delete([]byte(fmt.Sprintf("%s_content", cacheKey))); err != nil {
+	var found bool
+
+	err := c.Cache.View(func(txn *badger.Txn) error {
+		var err error
+		_, found, err = readItem(txn, namespacedKey(c.Config, cacheKey))
 		return err
+	})
+	if err != nil {
+		return false, op.fail(err)
 	}
 
-	// Delete the item by key
-	if err := txn.Delete([]byte(fmt.Sprintf("%s_ttl", cacheKey))); err != nil {
-		return err
+	op.hit(found)
+
+	return found, nil
+}
+
+// The `Clear` function is used to remove all items from the cache. It drops all the data stored in the
+// Badger database, or only the keys of its namespace if the cache has a `Namespace`.
+func (c *BadgerCache) Clear(ctx context.Context) error {
+	_, op := c.telemetry.start(ctx, "Clear")
+	defer op.end()
+
+	return op.fail(c.clearPrefix(""))
+}
+
+// The `ClearPrefix` function is used to remove all items whose cache keys start with the given prefix,
+// using Badger's `DropPrefix`. Legacy keys of such items share the prefix and are dropped as well.
+// Writes are blocked while the prefix is dropped.
+func (c *BadgerCache) ClearPrefix(ctx context.Context, prefix string) error {
+	_, op := c.telemetry.start(ctx, "ClearPrefix")
+	defer op.end()
+
+	return op.fail(c.clearPrefix(prefix))
+}
+
+// The `clearPrefix` function drops the keys starting with the given prefix, or all keys if neither the
+// prefix nor the namespace is set.
+func (c *BadgerCache) clearPrefix(prefix string) error {
+	key := namespacedKey(c.Config, prefix)
+	if key == "" {
+		return c.Cache.DropAll()
 	}
 
-	// Commit the transaction
-	if err := txn.Commit(); err != nil {
-		return err
+	return c.Cache.DropPrefix([]byte(key))
+}
+
+// The `Close` function is used to close the Badger database. It stops the background maintenance loops,
+// flushes pending writes to disk and releases the directory lock, so the same path can be opened again.
+// The cache must not be used after it has been closed.
+func (c *BadgerCache) Close(ctx context.Context) error {
+	_, op := c.telemetry.start(ctx, "Close")
+	defer op.end()
+
+	c.janitor.Stop()
+	c.sizeJanitor.Stop()
+
+	return op.fail(errors.Join(c.telemetry.close(), c.Cache.Close()))
+}
+
+// The `GetMany` function is used to retrieve multiple items from the cache within a single read
+// transaction. It returns a map of the found items by their cache keys; missing and expired keys are
+// omitted. Items still stored in the legacy layout are migrated afterwards.
+func (c *BadgerCache) GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error) {
+	_, op := c.telemetry.start(ctx, "GetMany")
+	defer op.end()
+
+	op.keys(len(cacheKeys))
+
+	items := make(map[string][]byte, len(cacheKeys))
+	legacy := map[string]badgerItem{}
+
+	err := c.Cache.View(func(txn *badger.Txn) error {
+		for _, cacheKey := range cacheKeys {
+			item, found, err := readItem(txn, namespacedKey(c.Config, cacheKey))
+			if err != nil {
+				return err
+			}
+
+			if !found {
+				continue
+			}
+
+			if item.legacy {
+				legacy[cacheKey] = item
+				continue
+			}
+
+			if value, ok := unwrapValue(item.value); ok {
+				items[cacheKey] = value
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, op.fail(err)
+	}
+
+	for cacheKey, item := range legacy {
+		if item, found := c.migrateLegacyItem(namespacedKey(c.Config, cacheKey), item); found {
+			if entry, err := item.entry(); err == nil {
+				items[cacheKey] = entry.Value
+			}
+		}
+	}
+
+	op.lookup(len(cacheKeys), len(items))
+
+	return items, nil
+}
+
+// The `SetMany` function is used to store multiple items in the cache with the TTL from the cache
+// configuration. The items are written with a single `WriteBatch`.
+func (c *BadgerCache) SetMany(ctx context.Context, items map[string][]byte) error {
+	_, op := c.telemetry.start(ctx, "SetMany")
+	defer op.end()
+
+	op.keys(len(items))
+
+	wb := c.Cache.NewWriteBatch()
+	defer wb.Cancel()
+
+	for cacheKey, item := range items {
+		if err := wb.SetEntry(newBadgerEntry(namespacedKey(c.Config, cacheKey), encodeValue(item), c.Config.TTL)); err != nil {
+			return op.fail(err)
+		}
 	}
 
-	return nil
+	return op.fail(wb.Flush())
 }
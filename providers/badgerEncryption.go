@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+)
+
+// `defaultEncryptedIndexCacheSize` is the index cache size used for encrypted databases when
+// `BadgerIndexCacheSize` is not set.
+const defaultEncryptedIndexCacheSize = 100 << 20
+
+// The `encryptionKey` function returns the Badger encryption key from the cache configuration. The key
+// is taken from `BadgerEncryptionKey`, the file named by `BadgerEncryptionKeyFile` or the environment
+// variable named by `BadgerEncryptionKeyEnv`, in that order. It returns nil if encryption is not
+// configured, and an error if the key can't be read or has an invalid length.
+func (c *BadgerCache) encryptionKey() ([]byte, error) {
+	var key []byte
+
+	switch {
+	case c.Config.BadgerEncryptionKey != "":
+		key = []byte(c.Config.BadgerEncryptionKey)
+
+	case c.Config.BadgerEncryptionKeyFile != "":
+		data, err := os.ReadFile(c.Config.BadgerEncryptionKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read badger encryption key file: %w", err)
+		}
+
+		key = data
+
+	case c.Config.BadgerEncryptionKeyEnv != "":
+		value, ok := os.LookupEnv(c.Config.BadgerEncryptionKeyEnv)
+		if !ok || value == "" {
+			return nil, fmt.Errorf("badger encryption key environment variable %q is not set", c.Config.BadgerEncryptionKeyEnv)
+		}
+
+		key = []byte(value)
+
+	default:
+		return nil, nil
+	}
+
+	// Keys stored in files and environment variables often end with a newline that is not part of the
+	// key itself
+	if !validAESKeyLength(len(key)) {
+		key = bytes.TrimRight(key, "\r\n")
+	}
+
+	if !validAESKeyLength(len(key)) {
+		return nil, fmt.Errorf("badger encryption key must be 16, 24 or 32 bytes long, got %d bytes", len(key))
+	}
+
+	return key, nil
+}
+
+// The `validAESKeyLength` function reports whether the length is valid for an AES-128, AES-192 or
+// AES-256 key.
+func validAESKeyLength(length int) bool {
+	return length == 16 || length == 24 || length == 32
+}
@@ -0,0 +1,121 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+)
+
+// Earlier versions of the BadgerCache stored every item as two keys: `<key>_content` with the content
+// and `<key>_ttl` with the JSON-encoded expiry time (a zero time meaning no expiry). The functions in
+// this file read that layout and migrate such items to a single entry with a native TTL. Legacy keys
+// carry no user metadata byte, which tells them apart from entries written by the current layout.
+
+// The `legacyContentKey` function returns the key of the content of an item in the legacy layout.
+func legacyContentKey(cacheKey string) string {
+	return fmt.Sprintf("%s_content", cacheKey)
+}
+
+// The `legacyTTLKey` function returns the key of the expiry time of an item in the legacy layout.
+func legacyTTLKey(cacheKey string) string {
+	return fmt.Sprintf("%s_ttl", cacheKey)
+}
+
+// The `readLegacyItem` function reads an item stored in the legacy two-key layout within the provided
+// transaction. Expired legacy items are reported as not found, their keys stay in place until the item
+// is deleted.
+func readLegacyItem(txn *badger.Txn, cacheKey string) (badgerItem, bool, error) {
+	item := badgerItem{legacy: true}
+
+	itemTTL, err := txn.Get([]byte(legacyTTLKey(cacheKey)))
+	switch {
+	case err == badger.ErrKeyNotFound:
+		return item, false, nil
+	case err != nil:
+		return item, false, err
+	case itemTTL.UserMeta() != 0:
+		return item, false, nil
+	}
+
+	err = itemTTL.Value(func(val []byte) error {
+		// Deserialize the value into the appropriate type
+		return json.Unmarshal(val, &item.expiresAt)
+	})
+	if err != nil {
+		return item, false, err
+	}
+
+	if isExpired(item.expiresAt) {
+		return item, false, nil
+	}
+
+	itemContent, err := txn.Get([]byte(legacyContentKey(cacheKey)))
+	switch {
+	case err == badger.ErrKeyNotFound:
+		return item, false, nil
+	case err != nil:
+		return item, false, err
+	case itemContent.UserMeta() != 0:
+		return item, false, nil
+	}
+
+	item.value, err = itemContent.ValueCopy(nil)
+	if err != nil {
+		return item, false, err
+	}
+
+	return item, true, nil
+}
+
+// The `migrateLegacyItem` function rewrites a legacy item as a single entry with its remaining TTL and
+// deletes its legacy keys in one transaction. The item is read again within the transaction and only
+// migrated if it is still stored in the legacy layout, so an item written or deleted since it was read
+// is never overwritten with the old content; the current item is returned instead. The item is returned
+// even if the migration fails (e.g. on a read-only database), it will be retried on the next read.
+func (c *BadgerCache) migrateLegacyItem(cacheKey string, item badgerItem) (badgerItem, bool) {
+	for {
+		var current badgerItem
+		var found bool
+
+		err := c.Cache.Update(func(txn *badger.Txn) error {
+			var err error
+			current, found, err = readItem(txn, cacheKey)
+			if err != nil || !found || !current.legacy {
+				return err
+			}
+
+			if err := deleteLegacyItem(txn, cacheKey); err != nil {
+				return err
+			}
+
+			return txn.SetEntry(newBadgerEntry(cacheKey, current.stored(), current.ttl()))
+		})
+
+		switch {
+		case err == badger.ErrConflict:
+			// The item was written concurrently, the next attempt reads the new item
+			continue
+		case err != nil:
+			return item, true
+		}
+
+		return current, found
+	}
+}
+
+// The `deleteLegacyItem` function deletes the legacy keys of an item within the provided transaction.
+func deleteLegacyItem(txn *badger.Txn, cacheKey string) error {
+	if err := deleteWithMeta(txn, legacyContentKey(cacheKey), 0); err != nil {
+		return err
+	}
+
+	return deleteWithMeta(txn, legacyTTLKey(cacheKey), 0)
+}
+
+// The `isExpired` function reports whether an expiry time is in the past. A zero expiry time means the
+// item never expires.
+func isExpired(ttl time.Time) bool {
+	return !ttl.IsZero() && ttl.Unix() <= time.Now().Unix()
+}
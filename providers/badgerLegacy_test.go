@@ -0,0 +1,297 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"github.com/wasilak/cachego/config"
+	"go.opentelemetry.io/otel"
+)
+
+func newTestBadgerCache(t *testing.T) *BadgerCache {
+	t.Helper()
+
+	c := &BadgerCache{Config: config.Config{
+		Type:           "badger",
+		TTL:            time.Minute,
+		BadgerInMemory: true,
+		Tracer:         otel.Tracer("test"),
+	}}
+
+	if err := c.Init(context.Background()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	t.Cleanup(func() { c.Close(context.Background()) })
+
+	return c
+}
+
+// writeLegacyItem stores an item in the legacy two-key layout, the way earlier versions did.
+func writeLegacyItem(t *testing.T, c *BadgerCache, cacheKey string, value []byte, expiresAt time.Time) {
+	t.Helper()
+
+	ttl, err := json.Marshal(expiresAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = c.Cache.Update(func(txn *badger.Txn) error {
+		if err := txn.Set([]byte(legacyContentKey(cacheKey)), value); err != nil {
+			return err
+		}
+
+		return txn.Set([]byte(legacyTTLKey(cacheKey)), ttl)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// legacyKeysExist reports whether any of the legacy keys of an item are still stored.
+func legacyKeysExist(t *testing.T, c *BadgerCache, cacheKey string) bool {
+	t.Helper()
+
+	exist := false
+
+	err := c.Cache.View(func(txn *badger.Txn) error {
+		for _, key := range []string{legacyContentKey(cacheKey), legacyTTLKey(cacheKey)} {
+			_, err := txn.Get([]byte(key))
+			switch {
+			case err == nil:
+				exist = true
+			case err != badger.ErrKeyNotFound:
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return exist
+}
+
+func TestBadgerLegacyItemIsMigratedOnRead(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBadgerCache(t)
+
+	writeLegacyItem(t, c, "user", []byte("alice"), time.Now().Add(time.Hour))
+
+	item, found, err := c.Get(ctx, "user")
+	if err != nil || !found || string(item) != "alice" {
+		t.Fatalf("Get() = %q, %v, %v, want %q, true, nil", item, found, err, "alice")
+	}
+
+	if legacyKeysExist(t, c, "user") {
+		t.Error("legacy keys still exist after migration")
+	}
+
+	item, found, err = c.Get(ctx, "user")
+	if err != nil || !found || string(item) != "alice" {
+		t.Fatalf("Get() after migration = %q, %v, %v, want %q, true, nil", item, found, err, "alice")
+	}
+
+	ttl, found, err := c.GetItemTTL(ctx, "user")
+	if err != nil || !found || ttl <= 59*time.Minute || ttl > time.Hour+time.Second {
+		t.Errorf("GetItemTTL() = %v, %v, %v, want about an hour", ttl, found, err)
+	}
+}
+
+func TestBadgerLegacyItemWithoutExpiry(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBadgerCache(t)
+
+	writeLegacyItem(t, c, "forever", []byte("v"), time.Time{})
+
+	if _, found, err := c.Get(ctx, "forever"); err != nil || !found {
+		t.Fatalf("Get() = %v, %v, want true, nil", found, err)
+	}
+
+	ttl, found, err := c.GetItemTTL(ctx, "forever")
+	if err != nil || !found || ttl != config.NoExpiration {
+		t.Errorf("GetItemTTL() = %v, %v, %v, want NoExpiration", ttl, found, err)
+	}
+}
+
+func TestBadgerLegacyItemStartingWithEnvelopeMagic(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBadgerCache(t)
+
+	value := append(append([]byte{}, envelopeMagic...), envelopeEntry, 1, 2, 3)
+	writeLegacyItem(t, c, "user", value, time.Now().Add(time.Hour))
+
+	// The first read migrates the item, the second one reads the migrated entry
+	for _, read := range []string{"legacy", "migrated"} {
+		if item, found, err := c.Get(ctx, "user"); err != nil || !found || !bytes.Equal(item, value) {
+			t.Errorf("Get() of the %s item = %q, %v, %v, want %q", read, item, found, err, value)
+		}
+	}
+
+	if ttl, found, err := c.GetItemTTL(ctx, "user"); err != nil || !found || ttl < 59*time.Minute {
+		t.Errorf("GetItemTTL() = %v, %v, %v, want about an hour", ttl, found, err)
+	}
+}
+
+func TestBadgerLegacyMigrationAfterConcurrentWrite(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		write     func(c *BadgerCache) error
+		want      string
+		wantFound bool
+	}{
+		{
+			name:      "Set",
+			write:     func(c *BadgerCache) error { return c.Set(ctx, "user", []byte("new")) },
+			want:      "new",
+			wantFound: true,
+		},
+		{
+			name:  "Delete",
+			write: func(c *BadgerCache) error { return c.Delete(ctx, "user") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newTestBadgerCache(t)
+
+			writeLegacyItem(t, c, "user", []byte("old"), time.Now().Add(time.Hour))
+
+			var legacy badgerItem
+			err := c.Cache.View(func(txn *badger.Txn) error {
+				var err error
+				legacy, _, err = readItem(txn, "user")
+				return err
+			})
+			if err != nil || !legacy.legacy {
+				t.Fatalf("readItem() = %+v, %v, want a legacy item", legacy, err)
+			}
+
+			// The write lands between the read of the legacy item and its migration
+			if err := tt.write(c); err != nil {
+				t.Fatal(err)
+			}
+
+			migrated, found := c.migrateLegacyItem("user", legacy)
+			if entry, _ := migrated.entry(); found != tt.wantFound || string(entry.Value) != tt.want {
+				t.Errorf("migrateLegacyItem() = %q, %v, want %q, %v", entry.Value, found, tt.want, tt.wantFound)
+			}
+
+			item, found, err := c.Get(ctx, "user")
+			if err != nil || found != tt.wantFound || string(item) != tt.want {
+				t.Errorf("Get() = %q, %v, %v, want %q, %v", item, found, err, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestBadgerExpiredLegacyItemIsSwept(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBadgerCache(t)
+
+	writeLegacyItem(t, c, "old", []byte("v"), time.Now().Add(-time.Hour))
+	writeLegacyItem(t, c, "fresh", []byte("v"), time.Now().Add(time.Hour))
+
+	if _, found, err := c.Get(ctx, "old"); err != nil || found {
+		t.Fatalf("Get() of expired legacy item = %v, %v, want false, nil", found, err)
+	}
+
+	swept, err := c.sweepLegacyItems(ctx)
+	if err != nil || swept != 1 {
+		t.Fatalf("sweepLegacyItems() = %d, %v, want 1, nil", swept, err)
+	}
+
+	if legacyKeysExist(t, c, "old") {
+		t.Error("legacy keys of the expired item still exist after the sweep")
+	}
+
+	if !legacyKeysExist(t, c, "fresh") {
+		t.Error("legacy keys of the fresh item were swept")
+	}
+
+	if c.legacySweepDone {
+		t.Error("legacySweepDone is set while legacy items are left")
+	}
+}
+
+func TestBadgerKeysEndingWithLegacySuffixes(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBadgerCache(t)
+
+	if err := c.Set(ctx, "a", []byte("item")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set(ctx, "a_ttl", []byte("not json")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Set(ctx, "a_content", []byte("other")); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]string{"a": "item", "a_ttl": "not json", "a_content": "other"} {
+		item, found, err := c.Get(ctx, key)
+		if err != nil || !found || string(item) != want {
+			t.Errorf("Get(%q) = %q, %v, %v, want %q, true, nil", key, item, found, err, want)
+		}
+	}
+
+	if swept, err := c.sweepLegacyItems(ctx); err != nil || swept != 0 {
+		t.Errorf("sweepLegacyItems() = %d, %v, want 0, nil", swept, err)
+	}
+
+	if !c.legacySweepDone {
+		t.Error("legacySweepDone is not set without legacy items")
+	}
+
+	if err := c.Delete(ctx, "a"); err != nil {
+		t.Fatal(err)
+	}
+
+	if found, _ := c.Has(ctx, "a_ttl"); !found {
+		t.Error("deleting an item removed a key ending with _ttl")
+	}
+}
+
+func TestBadgerSubSecondTTL(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBadgerCache(t)
+
+	if err := c.SetWithTTL(ctx, "short", []byte("v"), 400*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, found, err := c.Get(ctx, "short"); err != nil || !found {
+		t.Fatalf("Get() right after SetWithTTL(400ms) = %v, %v, want true, nil", found, err)
+	}
+}
+
+func TestBadgerExpiresAtRoundsUp(t *testing.T) {
+	now := time.Unix(100, 700*int64(time.Millisecond))
+
+	tests := []struct {
+		ttl  time.Duration
+		want uint64
+	}{
+		{400 * time.Millisecond, 102},
+		{300 * time.Millisecond, 101},
+		{time.Second, 102},
+		{time.Minute, 161},
+	}
+
+	for _, tt := range tests {
+		if got := badgerExpiresAt(now, tt.ttl); got != tt.want {
+			t.Errorf("badgerExpiresAt(%v) = %d, want %d", tt.ttl, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package providers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v4"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// `sweepBatchSize` is the maximum number of expired legacy items deleted in a single transaction.
+const sweepBatchSize = 1000
+
+// The `runMaintenance` function is run periodically by the janitor started in `Init`. It sweeps expired
+// items of the legacy two-key layout, which Badger can't expire on its own, and then runs the value log
+// GC, so the cache directory doesn't keep growing. The sweep is skipped once a run finds no legacy keys
+// left, as the current layout never writes them. A value log growing faster than the maintenance runs
+// is collected by `checkValueLogSize`. The results are reported as attributes of the `Maintenance` span.
+func (c *BadgerCache) runMaintenance() {
+	ctx, span := c.Config.Tracer.Start(context.Background(), "Maintenance")
+	defer span.End()
+
+	if !c.legacySweepDone {
+		swept, err := c.sweepLegacyItems(ctx)
+		span.SetAttributes(attribute.Int("cache.badger.swept_items", swept))
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+	}
+
+	rewrites, err := c.runValueLogGC(ctx)
+	span.SetAttributes(attribute.Int("cache.badger.vlog_gc_rewrites", rewrites))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// The `sweepLegacyItems` function deletes all expired items stored in the legacy two-key layout and
+// returns the number of deleted items. The keys are iterated without prefetching their values, only
+// the values of legacy `_ttl` keys are read. When no legacy keys are found, `legacySweepDone` is set.
+func (c *BadgerCache) sweepLegacyItems(ctx context.Context) (int, error) {
+	_, span := c.Config.Tracer.Start(ctx, "SweepLegacyItems")
+	defer span.End()
+
+	var expired []string
+	legacy := 0
+
+	err := c.Cache.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			entry := it.Item()
+
+			cacheKey, ok := strings.CutSuffix(string(entry.Key()), "_ttl")
+			if !ok || entry.UserMeta() != 0 {
+				continue
+			}
+
+			legacy++
+
+			err := entry.Value(func(val []byte) error {
+				var expiresAt time.Time
+				if err := json.Unmarshal(val, &expiresAt); err != nil {
+					return err
+				}
+
+				if isExpired(expiresAt) {
+					expired = append(expired, cacheKey)
+				}
+
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	span.SetAttributes(attribute.Int("cache.badger.legacy_items", legacy))
+	c.legacySweepDone = legacy == 0
+
+	for start := 0; start < len(expired); start += sweepBatchSize {
+		batch := expired[start:min(start+sweepBatchSize, len(expired))]
+
+		err := c.Cache.Update(func(txn *badger.Txn) error {
+			for _, cacheKey := range batch {
+				if err := deleteLegacyItem(txn, cacheKey); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return start, err
+		}
+	}
+
+	return len(expired), nil
+}
+
+// The `checkValueLogSize` function is run periodically by the size janitor started in `Init`. It runs
+// the value log GC as soon as the value log grows past `BadgerGCSizeThreshold`, without waiting for the
+// next maintenance run. The results are reported as attributes of the `ValueLogSizeCheck` span.
+func (c *BadgerCache) checkValueLogSize() {
+	ctx, span := c.Config.Tracer.Start(context.Background(), "ValueLogSizeCheck")
+	defer span.End()
+
+	_, vlog := c.Cache.Size()
+	span.SetAttributes(attribute.Int64("cache.badger.vlog_size", vlog))
+
+	if !c.valueLogGCDue(vlog) {
+		return
+	}
+
+	rewrites, err := c.runValueLogGC(ctx)
+	span.SetAttributes(attribute.Int("cache.badger.vlog_gc_rewrites", rewrites))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
+
+// The `valueLogGCDue` function reports whether a value log of the given size triggers the value log GC.
+func (c *BadgerCache) valueLogGCDue(vlog int64) bool {
+	return c.Config.BadgerGCSizeThreshold > 0 && vlog >= c.Config.BadgerGCSizeThreshold
+}
+
+// The `runValueLogGC` function runs the Badger value log GC until there is nothing left to rewrite and
+// returns the number of rewritten value log files.
+func (c *BadgerCache) runValueLogGC(ctx context.Context) (int, error) {
+	_, span := c.Config.Tracer.Start(ctx, "ValueLogGC")
+	defer span.End()
+
+	rewrites := 0
+
+	for {
+		err := c.Cache.RunValueLogGC(c.Config.BadgerGCDiscardRatio)
+		switch {
+		case err == nil:
+			rewrites++
+		case errors.Is(err, badger.ErrNoRewrite), errors.Is(err, badger.ErrRejected):
+			return rewrites, nil
+		default:
+			return rewrites, err
+		}
+	}
+}
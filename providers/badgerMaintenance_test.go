@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"go.opentelemetry.io/otel"
+)
+
+func TestBadgerValueLogGCDue(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int64
+		vlog      int64
+		want      bool
+	}{
+		{"disabled", 0, 1 << 30, false},
+		{"below the threshold", 1 << 20, 1<<20 - 1, false},
+		{"at the threshold", 1 << 20, 1 << 20, true},
+		{"past the threshold", 1 << 20, 1 << 30, true},
+	}
+
+	for _, tt := range tests {
+		c := &BadgerCache{Config: config.Config{BadgerGCSizeThreshold: tt.threshold}}
+		if got := c.valueLogGCDue(tt.vlog); got != tt.want {
+			t.Errorf("%s: valueLogGCDue(%d) = %v, want %v", tt.name, tt.vlog, got, tt.want)
+		}
+	}
+}
+
+func TestBadgerSizeJanitor(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		interval  time.Duration
+		threshold int64
+		want      bool
+	}{
+		{name: "size trigger", interval: time.Hour, threshold: 1 << 20, want: true},
+		{name: "no threshold", interval: time.Hour},
+		{name: "maintenance disabled", interval: -1, threshold: 1 << 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &BadgerCache{
+				Path: t.TempDir(),
+				Config: config.Config{
+					Type:                      "badger",
+					TTL:                       time.Minute,
+					BadgerGCInterval:          tt.interval,
+					BadgerGCDiscardRatio:      0.5,
+					BadgerGCSizeThreshold:     tt.threshold,
+					BadgerGCSizeCheckInterval: 10 * time.Millisecond,
+					Tracer:                    otel.Tracer("test"),
+				},
+			}
+
+			if err := c.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := c.sizeJanitor != nil; got != tt.want {
+				t.Fatalf("size janitor started = %v, want %v", got, tt.want)
+			}
+
+			if err := c.Set(ctx, "k", make([]byte, 1<<16)); err != nil {
+				t.Fatal(err)
+			}
+
+			// Let the size janitor tick a few times next to the writes
+			time.Sleep(50 * time.Millisecond)
+
+			if rewrites, err := c.runValueLogGC(ctx); err != nil {
+				t.Errorf("runValueLogGC() = %d, %v, want no error", rewrites, err)
+			}
+
+			if err := c.Close(ctx); err != nil {
+				t.Errorf("Close() error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package providers
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+)
+
+// Items loaded by `GetOrLoad` with `StaleWhileRevalidate`, `StaleIfError` or `EarlyExpirationBeta`
+// enabled are stored with `SetEntry` in an envelope carrying their soft expiry, the end of their
+// regular TTL, and the time their loader took. The envelope is a storage detail of the providers: `Get`
+// and `GetMany` return the item without it and `GetItemTTL` reports the time left until the soft
+// expiry, so readers other than `GetOrLoad` never see it.
+//
+// The envelope shares the value space with the items, so items written with `Set`, `SetWithTTL` or
+// `SetMany` that happen to start with `envelopeMagic` are escaped with a raw envelope holding the item
+// unchanged. Every other item is stored as is, so values written by earlier versions or by other
+// clients read back unchanged unless they start with the magic.
+
+// `envelopeMagic` starts every envelope.
+var envelopeMagic = []byte{0x00, 's', 'w'}
+
+// The kinds of envelopes, stored after `envelopeMagic`. A raw envelope is followed by the item. An entry
+// envelope is followed by the soft expiry as Unix nanoseconds and the load duration in nanoseconds
+// (8 bytes each, big endian) and then the item.
+const (
+	envelopeRaw   byte = 0
+	envelopeEntry byte = 1
+)
+
+// The sizes of the envelope headers preceding the item.
+const (
+	rawEnvelopeHeaderSize = 3 + 1
+	envelopeHeaderSize    = rawEnvelopeHeaderSize + 8 + 8
+)
+
+// `errInvalidEnvelope` is returned for values that start with `envelopeMagic` but can't be decoded,
+// e.g. ones written by a newer version. Such values are treated as missing.
+var errInvalidEnvelope = errors.New("invalid entry envelope")
+
+// The `Entry` type represents an item together with the metadata used by `GetOrLoad` to serve it stale
+// or reload it early.
+// @property Value - The `Value` property is the item.
+// @property SoftExpiry - The `SoftExpiry` property is the time after which the item is stale. A zero
+// time means the item has no soft expiry and is stored without an envelope.
+// @property LoadDuration - The `LoadDuration` property is how long the loader took to produce the item,
+// zero if unknown.
+type Entry struct {
+	Value        []byte
+	SoftExpiry   time.Time
+	LoadDuration time.Duration
+}
+
+// The `encodeValue` function returns the stored form of an item written without entry metadata: the
+// item itself, or the item in a raw envelope if it starts with `envelopeMagic`.
+func encodeValue(item []byte) []byte {
+	if !bytes.HasPrefix(item, envelopeMagic) {
+		return item
+	}
+
+	stored := make([]byte, 0, rawEnvelopeHeaderSize+len(item))
+	stored = append(stored, envelopeMagic...)
+	stored = append(stored, envelopeRaw)
+	stored = append(stored, item...)
+
+	return stored
+}
+
+// The `encodeEntry` function returns the stored form of an entry: the value wrapped in an entry
+// envelope, or the value as encoded by `encodeValue` if the entry has no soft expiry.
+func encodeEntry(entry Entry) []byte {
+	if entry.SoftExpiry.IsZero() {
+		return encodeValue(entry.Value)
+	}
+
+	stored := make([]byte, 0, envelopeHeaderSize+len(entry.Value))
+	stored = append(stored, envelopeMagic...)
+	stored = append(stored, envelopeEntry)
+	stored = binary.BigEndian.AppendUint64(stored, uint64(entry.SoftExpiry.UnixNano()))
+	stored = binary.BigEndian.AppendUint64(stored, uint64(entry.LoadDuration))
+	stored = append(stored, entry.Value...)
+
+	return stored
+}
+
+// The `decodeEntry` function unwraps a stored value. Values without an envelope and values in a raw
+// envelope are returned as an entry without a soft expiry.
+func decodeEntry(stored []byte) (Entry, error) {
+	if !bytes.HasPrefix(stored, envelopeMagic) {
+		return Entry{Value: stored}, nil
+	}
+
+	if len(stored) < rawEnvelopeHeaderSize {
+		return Entry{}, errInvalidEnvelope
+	}
+
+	switch stored[len(envelopeMagic)] {
+	case envelopeRaw:
+		return Entry{Value: stored[rawEnvelopeHeaderSize:]}, nil
+	case envelopeEntry:
+		if len(stored) < envelopeHeaderSize {
+			return Entry{}, errInvalidEnvelope
+		}
+
+		return Entry{
+			Value:        stored[envelopeHeaderSize:],
+			SoftExpiry:   time.Unix(0, int64(binary.BigEndian.Uint64(stored[rawEnvelopeHeaderSize:]))),
+			LoadDuration: time.Duration(binary.BigEndian.Uint64(stored[rawEnvelopeHeaderSize+8:])),
+		}, nil
+	}
+
+	return Entry{}, errInvalidEnvelope
+}
+
+// The `unwrapValue` function returns the item of a stored value without its envelope. It returns false
+// for values with an invalid envelope, which are reported as missing.
+func unwrapValue(stored []byte) ([]byte, bool) {
+	entry, err := decodeEntry(stored)
+	if err != nil {
+		return nil, false
+	}
+
+	return entry.Value, true
+}
+
+// The `entryTTL` function returns the TTL reported by `GetItemTTL` for an item with the given remaining
+// TTL in the backend and stored value (or at least its `envelopeHeaderSize` first bytes). Items in an
+// envelope report the time left until their soft expiry, zero once they are stale.
+func entryTTL(ttl time.Duration, stored []byte, now time.Time) time.Duration {
+	entry, err := decodeEntry(stored)
+	if err != nil || entry.SoftExpiry.IsZero() {
+		return ttl
+	}
+
+	soft := max(entry.SoftExpiry.Sub(now), 0)
+	if ttl == config.NoExpiration || soft < ttl {
+		return soft
+	}
+
+	return ttl
+}
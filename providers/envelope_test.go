@@ -0,0 +1,267 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"go.opentelemetry.io/otel"
+)
+
+func TestEntryRoundTrip(t *testing.T) {
+	entry := Entry{
+		Value:        []byte(`{"a":1}`),
+		SoftExpiry:   time.Unix(0, 1700000000123456789),
+		LoadDuration: 250 * time.Millisecond,
+	}
+
+	stored := encodeEntry(entry)
+	if !bytes.HasPrefix(stored, envelopeMagic) || stored[len(envelopeMagic)] != envelopeEntry {
+		t.Fatalf("encodeEntry() = %q, want an entry envelope", stored)
+	}
+
+	got, err := decodeEntry(stored)
+	if err != nil {
+		t.Fatalf("decodeEntry() error = %v", err)
+	}
+
+	if !bytes.Equal(got.Value, entry.Value) || !got.SoftExpiry.Equal(entry.SoftExpiry) || got.LoadDuration != entry.LoadDuration {
+		t.Errorf("decodeEntry() = %+v, want %+v", got, entry)
+	}
+}
+
+func TestEncodeValue(t *testing.T) {
+	magic := string(envelopeMagic)
+
+	tests := []struct {
+		name string
+		item string
+		want string
+	}{
+		{name: "plain value", item: "plain", want: "plain"},
+		{name: "empty value", item: "", want: ""},
+		{name: "magic prefix only", item: magic[:2], want: magic[:2]},
+		{name: "magic", item: magic, want: magic + "\x00" + magic},
+		{name: "raw envelope", item: magic + "\x00v", want: magic + "\x00" + magic + "\x00v"},
+		{name: "entry envelope", item: magic + "\x01v", want: magic + "\x00" + magic + "\x01v"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := encodeValue([]byte(tt.item))
+			if string(stored) != tt.want {
+				t.Errorf("encodeValue() = %q, want %q", stored, tt.want)
+			}
+
+			if stored := encodeEntry(Entry{Value: []byte(tt.item)}); string(stored) != tt.want {
+				t.Errorf("encodeEntry() without a soft expiry = %q, want %q", stored, tt.want)
+			}
+
+			entry, err := decodeEntry(stored)
+			if err != nil || string(entry.Value) != tt.item || !entry.SoftExpiry.IsZero() {
+				t.Errorf("decodeEntry() = %+v, %v, want the item without a soft expiry", entry, err)
+			}
+		})
+	}
+}
+
+func TestDecodeEntry(t *testing.T) {
+	softExpiry := time.Unix(0, 1700000000000000000)
+	header := func(kind byte, n int) []byte {
+		return append(append(append([]byte{}, envelopeMagic...), kind), make([]byte, n)...)
+	}
+
+	tests := []struct {
+		name    string
+		stored  []byte
+		want    Entry
+		wantErr bool
+	}{
+		{name: "plain value", stored: []byte("plain"), want: Entry{Value: []byte("plain")}},
+		{name: "empty value", stored: []byte{}, want: Entry{Value: []byte{}}},
+		{name: "raw envelope", stored: append(header(envelopeRaw, 0), "\x00sw\x01"...), want: Entry{Value: []byte("\x00sw\x01")}},
+		{name: "empty item", stored: encodeEntry(Entry{Value: nil, SoftExpiry: softExpiry}), want: Entry{Value: []byte{}, SoftExpiry: softExpiry}},
+		{name: "magic without a kind", stored: envelopeMagic, wantErr: true},
+		{name: "truncated entry header", stored: header(envelopeEntry, 15), wantErr: true},
+		{name: "unknown kind", stored: header(9, 16), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeEntry(tt.stored)
+			if tt.wantErr {
+				if err != errInvalidEnvelope {
+					t.Fatalf("decodeEntry() error = %v, want errInvalidEnvelope", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("decodeEntry() error = %v", err)
+			}
+
+			if !bytes.Equal(got.Value, tt.want.Value) || !got.SoftExpiry.Equal(tt.want.SoftExpiry) || got.LoadDuration != tt.want.LoadDuration {
+				t.Errorf("decodeEntry() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEntryTTL(t *testing.T) {
+	now := time.Now()
+	fresh := encodeEntry(Entry{Value: []byte("v"), SoftExpiry: now.Add(time.Minute)})
+	stale := encodeEntry(Entry{Value: []byte("v"), SoftExpiry: now.Add(-time.Second)})
+
+	tests := []struct {
+		name   string
+		ttl    time.Duration
+		stored []byte
+		want   time.Duration
+	}{
+		{"plain value", 5 * time.Minute, []byte("v"), 5 * time.Minute},
+		{"fresh entry", 5 * time.Minute, fresh, time.Minute},
+		{"fresh entry without expiry", config.NoExpiration, fresh, time.Minute},
+		{"stale entry", 5 * time.Minute, stale, 0},
+		{"header only", 5 * time.Minute, fresh[:envelopeHeaderSize], time.Minute},
+		{"hard TTL shorter than soft", 30 * time.Second, fresh, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := entryTTL(tt.ttl, tt.stored, now); got != tt.want {
+			t.Errorf("%s: entryTTL() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func newTestGoCache(t *testing.T) *GoCache {
+	t.Helper()
+
+	c := &GoCache{Config: config.Config{Type: "memory", TTL: time.Minute, Tracer: otel.Tracer("test")}}
+	if err := c.Init(context.Background()); err != nil {
+		t.Fatalf("Init() error = %v", err)
+	}
+
+	t.Cleanup(func() { c.Close(context.Background()) })
+
+	return c
+}
+
+func TestGoCacheHidesEnvelope(t *testing.T) {
+	ctx := context.Background()
+	c := newTestGoCache(t)
+
+	entry := Entry{Value: []byte("v"), SoftExpiry: time.Now().Add(time.Minute), LoadDuration: time.Second}
+	if err := c.SetEntry(ctx, "k", entry, 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if item, found, err := c.Get(ctx, "k"); err != nil || !found || string(item) != "v" {
+		t.Errorf("Get() = %q, %v, %v, want %q, true, nil", item, found, err, "v")
+	}
+
+	if items, err := c.GetMany(ctx, []string{"k"}); err != nil || string(items["k"]) != "v" {
+		t.Errorf("GetMany() = %q, %v, want k=v", items, err)
+	}
+
+	if ttl, found, err := c.GetItemTTL(ctx, "k"); err != nil || !found || ttl > time.Minute || ttl < 59*time.Second {
+		t.Errorf("GetItemTTL() = %v, %v, %v, want the time left until the soft expiry", ttl, found, err)
+	}
+
+	got, found, err := c.GetEntry(ctx, "k")
+	if err != nil || !found || string(got.Value) != "v" || got.LoadDuration != time.Second {
+		t.Errorf("GetEntry() = %+v, %v, %v, want %+v", got, found, err, entry)
+	}
+}
+
+func TestBadgerCacheHidesEnvelope(t *testing.T) {
+	ctx := context.Background()
+	c := newTestBadgerCache(t)
+
+	entry := Entry{Value: []byte("v"), SoftExpiry: time.Now().Add(time.Minute), LoadDuration: time.Second}
+	if err := c.SetEntry(ctx, "k", entry, 2*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if item, found, err := c.Get(ctx, "k"); err != nil || !found || string(item) != "v" {
+		t.Errorf("Get() = %q, %v, %v, want %q, true, nil", item, found, err, "v")
+	}
+
+	if items, err := c.GetMany(ctx, []string{"k"}); err != nil || string(items["k"]) != "v" {
+		t.Errorf("GetMany() = %q, %v, want k=v", items, err)
+	}
+
+	if ttl, found, err := c.GetItemTTL(ctx, "k"); err != nil || !found || ttl > time.Minute || ttl < 59*time.Second {
+		t.Errorf("GetItemTTL() = %v, %v, %v, want the time left until the soft expiry", ttl, found, err)
+	}
+}
+
+// store is implemented by the providers whose stored values are tested.
+type store interface {
+	Get(ctx context.Context, cacheKey string) ([]byte, bool, error)
+	GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error)
+	GetItemTTL(ctx context.Context, cacheKey string) (time.Duration, bool, error)
+	GetEntry(ctx context.Context, cacheKey string) (Entry, bool, error)
+	Set(ctx context.Context, cacheKey string, item []byte) error
+	SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error
+	SetMany(ctx context.Context, items map[string][]byte) error
+}
+
+func TestValuesStartingWithEnvelopeMagic(t *testing.T) {
+	ctx := context.Background()
+	magic := string(envelopeMagic)
+
+	caches := map[string]func(t *testing.T) store{
+		"memory": func(t *testing.T) store { return newTestGoCache(t) },
+		"badger": func(t *testing.T) store { return newTestBadgerCache(t) },
+	}
+
+	items := []string{
+		magic,
+		magic + "\x00",
+		magic + "\x01" + string(make([]byte, 16)) + "looks like an entry",
+		magic + "\x02 unknown kind",
+	}
+
+	writes := map[string]func(c store, cacheKey string, item []byte) error{
+		"Set":        func(c store, cacheKey string, item []byte) error { return c.Set(ctx, cacheKey, item) },
+		"SetWithTTL": func(c store, cacheKey string, item []byte) error { return c.SetWithTTL(ctx, cacheKey, item, time.Hour) },
+		"SetMany": func(c store, cacheKey string, item []byte) error {
+			return c.SetMany(ctx, map[string][]byte{cacheKey: item})
+		},
+	}
+
+	for provider, newCache := range caches {
+		for write, set := range writes {
+			t.Run(provider+"/"+write, func(t *testing.T) {
+				c := newCache(t)
+
+				for i, item := range items {
+					cacheKey := fmt.Sprint(i)
+
+					if err := set(c, cacheKey, []byte(item)); err != nil {
+						t.Fatal(err)
+					}
+
+					if got, found, err := c.Get(ctx, cacheKey); err != nil || !found || string(got) != item {
+						t.Errorf("Get() = %q, %v, %v, want %q", got, found, err, item)
+					}
+
+					if got, err := c.GetMany(ctx, []string{cacheKey}); err != nil || string(got[cacheKey]) != item {
+						t.Errorf("GetMany() = %q, %v, want %q", got, err, item)
+					}
+
+					if entry, found, err := c.GetEntry(ctx, cacheKey); err != nil || !found || string(entry.Value) != item || !entry.SoftExpiry.IsZero() {
+						t.Errorf("GetEntry() = %+v, %v, %v, want %q without a soft expiry", entry, found, err, item)
+					}
+
+					if ttl, found, err := c.GetItemTTL(ctx, cacheKey); err != nil || !found || ttl < 59*time.Second {
+						t.Errorf("GetItemTTL() = %v, %v, %v, want the TTL of the item", ttl, found, err)
+					}
+				}
+			})
+		}
+	}
+}
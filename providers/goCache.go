@@ -1,6 +1,8 @@
 package providers
 
 import (
+	"context"
+	"sync"
 	"time"
 
 	gocache "github.com/patrickmn/go-cache"
@@ -19,9 +21,14 @@ import (
 // @property CTX - CTX is a context.Context object. It is used to carry request-scoped values across
 // API boundaries and between processes. It allows cancellation signals and request-scoped values to
 // propagate across API boundaries and between processes.
+// @property writeMu - The `writeMu` property serializes the writes made through the cache, so
+// `ExtendTTL` and `Touch` can rewrite an item without overwriting a concurrent `Set` or `Delete`.
 type GoCache struct {
-	Cache  *gocache.Cache
-	Config config.Config
+	Cache     *gocache.Cache
+	Config    config.Config
+	writeMu   sync.Mutex
+	janitor   *janitor
+	telemetry *telemetry
 }
 
 func (c *GoCache) GetConfig() config.Config {
@@ -31,68 +38,351 @@ func (c *GoCache) GetConfig() config.Config {
 // The `Init` function is initializing the cache by creating a new instance of `gocache.Cache` with the
 // specified time-to-live duration (`TTL`). It also starts a new span using the provided tracer and
 // context for tracing and monitoring purposes. Finally, it assigns the newly created cache to the
-// `Cache` property of the `GoCache` struct.
-func (c *GoCache) Init() error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Init")
-	defer span.End()
+// `Cache` property of the `GoCache` struct. Expired items are removed by a background janitor that is
+// stopped by `Close`. The number of items is reported by the `cache.items` gauge.
+func (c *GoCache) Init(ctx context.Context) error {
+	telemetry, err := newTelemetry(c.Config)
+	if err != nil {
+		return err
+	}
 
-	c.Cache = gocache.New(c.Config.TTL, c.Config.TTL)
+	c.telemetry = telemetry
 
-	return nil
+	_, op := c.telemetry.start(ctx, "Init")
+	defer op.end()
+
+	// The built-in go-cache janitor can only be stopped by the garbage collector, so it is disabled in
+	// favour of our own one.
+	c.Cache = gocache.New(c.Config.TTL, 0)
+	c.janitor = startJanitor(c.Config.TTL, c.Cache.DeleteExpired)
+
+	return op.fail(c.telemetry.registerGauges(c.Config, c.itemCount, nil))
+}
+
+// The `itemCount` function returns the number of items in the cache, or in its namespace if the cache
+// has a `Namespace`. Expired items that have not been removed by the janitor yet are counted as well.
+func (c *GoCache) itemCount(context.Context) (int64, error) {
+	if c.Config.Namespace == "" {
+		return int64(c.Cache.ItemCount()), nil
+	}
+
+	var count int64
+	for key := range c.Cache.Items() {
+		if hasNamespacePrefix(c.Config, key, "") {
+			count++
+		}
+	}
+
+	return count, nil
 }
 
-func (c *GoCache) Get(cacheKey string) ([]byte, bool, error) {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Get")
-	defer span.End()
+func (c *GoCache) Get(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	ctx, op := c.telemetry.start(ctx, "Get")
+	defer op.end()
+
+	op.key(cacheKey)
 
-	item, found := c.Cache.Get(cacheKey)
+	entry, found := c.getEntry(cacheKey)
 
-	if item == nil {
+	op.hit(found)
+
+	if !found {
+		op.lookup(1, 0)
 		var empty []byte
 		return empty, found, nil
 	}
 
-	return item.([]byte), found, nil
+	op.lookup(1, 1)
+	op.size(len(entry.Value))
+
+	return entry.Value, found, nil
+}
+
+// The `GetEntry` function is used to retrieve an item together with the soft expiry and load duration
+// it was stored with by `SetEntry`. It is used by `GetOrLoad`.
+func (c *GoCache) GetEntry(ctx context.Context, cacheKey string) (Entry, bool, error) {
+	_, op := c.telemetry.start(ctx, "GetEntry")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	entry, found := c.getEntry(cacheKey)
+
+	op.hit(found)
+
+	return entry, found, nil
+}
+
+// The `getEntry` function reads and unwraps an item. Items with an invalid envelope are reported as
+// missing.
+func (c *GoCache) getEntry(cacheKey string) (Entry, bool) {
+	item, found := c.Cache.Get(namespacedKey(c.Config, cacheKey))
+	if !found {
+		return Entry{}, false
+	}
+
+	entry, err := decodeEntry(item.([]byte))
+
+	return entry, err == nil
+}
+
+// The `SetEntry` function is used to store an item in an envelope with its soft expiry and load
+// duration, with the given TTL as its hard expiry. `Get` returns the item without the envelope. It is
+// used by `GetOrLoad`.
+func (c *GoCache) SetEntry(ctx context.Context, cacheKey string, entry Entry, ttl time.Duration) error {
+	_, op := c.telemetry.start(ctx, "SetEntry")
+	defer op.end()
+
+	return c.setWithTTL(op, cacheKey, encodeEntry(entry), ttl)
 }
 
 // The `Set` function is used to store an item in the cache. It takes two parameters: `cacheKey`, which
 // is a string representing the key for the item, and `item`, which is the actual item to be stored in
-// the cache. The function uses the `cacheKey` and `item` parameters to set the value in the cache
-// using the `gocache.Set` method. It also sets the time-to-live (TTL) for the item to the value
-// specified in the `TTL` property of the `GoCache` struct. Finally, it returns an error if any
-// occurred during the operation.
-func (c *GoCache) Set(cacheKey string, item []byte) error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Set")
-	defer span.End()
+// the cache. The item is stored with the TTL specified in the `TTL` property of the cache
+// configuration. Finally, it returns an error if any occurred during the operation.
+func (c *GoCache) Set(ctx context.Context, cacheKey string, item []byte) error {
+	_, op := c.telemetry.start(ctx, "Set")
+	defer op.end()
+
+	return c.setWithTTL(op, cacheKey, encodeValue(item), config.DefaultExpiration)
+}
+
+// The `SetWithTTL` function is used to store an item in the cache with its own time-to-live (TTL)
+// duration. `config.DefaultExpiration` uses the TTL from the cache configuration and
+// `config.NoExpiration` stores the item without an expiry.
+func (c *GoCache) SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error {
+	_, op := c.telemetry.start(ctx, "SetWithTTL")
+	defer op.end()
+
+	return c.setWithTTL(op, cacheKey, encodeValue(item), ttl)
+}
 
-	c.Cache.Set(cacheKey, item, c.Config.TTL)
+// The `setWithTTL` function stores an item, already in its stored form, with its own TTL under the
+// write lock. It is shared by the write methods, so each of them records its telemetry once.
+func (c *GoCache) setWithTTL(op *operation, cacheKey string, item []byte, ttl time.Duration) error {
+	op.key(cacheKey)
+	op.size(len(item))
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.Cache.Set(namespacedKey(c.Config, cacheKey), item, resolveTTL(ttl, c.Config))
 
 	return nil
 }
 
 // The `GetItemTTL` function is used to retrieve the remaining time-to-live (TTL) duration for a
 // specific item in the cache. It takes a `cacheKey` parameter, which is a string representing the key
-// of the item.
-func (c *GoCache) GetItemTTL(cacheKey string) (time.Duration, bool, error) {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "GetItemTTL")
-	defer span.End()
+// of the item. Items stored without an expiry report `config.NoExpiration`, and items stored with
+// `SetEntry` report the time left until their soft expiry.
+func (c *GoCache) GetItemTTL(ctx context.Context, cacheKey string) (time.Duration, bool, error) {
+	_, op := c.telemetry.start(ctx, "GetItemTTL")
+	defer op.end()
+
+	op.key(cacheKey)
 
-	_, expiration, found := c.Cache.GetWithExpiration(cacheKey)
+	item, expiration, found := c.Cache.GetWithExpiration(namespacedKey(c.Config, cacheKey))
+	if !found {
+		return 0, false, nil
+	}
 
 	now := time.Now()
-	difference := expiration.Sub(now)
 
-	return difference, found, nil
+	ttl := config.NoExpiration
+	if !expiration.IsZero() {
+		ttl = expiration.Sub(now)
+	}
+
+	return entryTTL(ttl, item.([]byte), now), true, nil
 }
 
 // The `ExtendTTL` function is used to extend the time-to-live (TTL) duration of a specific item in the
-// cache. It takes two parameters: `cacheKey`, which is a string representing the key of the item, and
-// `item`, which is the updated value of the item.
-func (c *GoCache) ExtendTTL(cacheKey string, item []byte) error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "ExtendTTL")
-	defer span.End()
+// cache by the given duration. It takes two parameters: `cacheKey`, which is a string representing the
+// key of the item, and `by`, which is added to the remaining TTL of the item. The value of the item is
+// left untouched and extending a missing item is a no-op. The item is rewritten under the write lock,
+// so a concurrent write is never overwritten with the old value.
+func (c *GoCache) ExtendTTL(ctx context.Context, cacheKey string, by time.Duration) error {
+	_, op := c.telemetry.start(ctx, "ExtendTTL")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	item, expiration, found := c.Cache.GetWithExpiration(namespacedKey(c.Config, cacheKey))
+	if !found {
+		return nil
+	}
 
-	c.Set(cacheKey, item)
+	remaining := config.NoExpiration
+	if !expiration.IsZero() {
+		remaining = time.Until(expiration)
+	}
+
+	c.Cache.Set(namespacedKey(c.Config, cacheKey), item, extendTTL(remaining, by))
+
+	return nil
+}
+
+// The `Touch` function is used to reset the time-to-live (TTL) duration of a specific item in the cache
+// to the given duration, counted from now, without changing its value. Touching a missing item is a
+// no-op. The item is rewritten under the write lock, like in `ExtendTTL`.
+func (c *GoCache) Touch(ctx context.Context, cacheKey string, ttl time.Duration) error {
+	_, op := c.telemetry.start(ctx, "Touch")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	item, found := c.Cache.Get(namespacedKey(c.Config, cacheKey))
+	if !found {
+		return nil
+	}
+
+	c.Cache.Set(namespacedKey(c.Config, cacheKey), item, resolveTTL(ttl, c.Config))
+
+	return nil
+}
+
+// The `Delete` function is used to remove an item from the cache based on the provided cache key.
+// Deleting a key that does not exist is not an error.
+func (c *GoCache) Delete(ctx context.Context, cacheKey string) error {
+	_, op := c.telemetry.start(ctx, "Delete")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.Cache.Delete(namespacedKey(c.Config, cacheKey))
+
+	return nil
+}
+
+// The `DeleteMany` function is used to remove multiple items from the cache at once.
+func (c *GoCache) DeleteMany(ctx context.Context, cacheKeys []string) error {
+	_, op := c.telemetry.start(ctx, "DeleteMany")
+	defer op.end()
+
+	op.keys(len(cacheKeys))
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	for _, cacheKey := range cacheKeys {
+		c.Cache.Delete(namespacedKey(c.Config, cacheKey))
+	}
+
+	return nil
+}
+
+// The `Has` function is used to check if an item exists in the cache and has not expired yet.
+func (c *GoCache) Has(ctx context.Context, cacheKey string) (bool, error) {
+	_, op := c.telemetry.start(ctx, "Has")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	_, found := c.Cache.Get(namespacedKey(c.Config, cacheKey))
+
+	op.hit(found)
+
+	return found, nil
+}
+
+// The `Clear` function is used to remove all items from the cache using the `gocache.Flush` method.
+// A cache with a `Namespace` only removes the items of its namespace.
+func (c *GoCache) Clear(ctx context.Context) error {
+	_, op := c.telemetry.start(ctx, "Clear")
+	defer op.end()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	if c.Config.Namespace != "" {
+		c.clearPrefix("")
+		return nil
+	}
+
+	c.Cache.Flush()
+
+	return nil
+}
+
+// The `ClearPrefix` function is used to remove all items whose cache keys start with the given prefix.
+func (c *GoCache) ClearPrefix(ctx context.Context, prefix string) error {
+	_, op := c.telemetry.start(ctx, "ClearPrefix")
+	defer op.end()
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	c.clearPrefix(prefix)
+
+	return nil
+}
+
+// The `clearPrefix` function removes all items whose cache keys start with the given prefix. The
+// caller must hold the write lock.
+func (c *GoCache) clearPrefix(prefix string) {
+	for key := range c.Cache.Items() {
+		if hasNamespacePrefix(c.Config, key, prefix) {
+			c.Cache.Delete(key)
+		}
+	}
+}
+
+// The `Close` function stops the background janitor and removes all items from the cache. The cache
+// must not be used after it has been closed.
+func (c *GoCache) Close(ctx context.Context) error {
+	_, op := c.telemetry.start(ctx, "Close")
+	defer op.end()
+
+	c.janitor.Stop()
+	c.Cache.Flush()
+
+	return op.fail(c.telemetry.close())
+}
+
+// The `GetMany` function is used to retrieve multiple items from the cache. It returns a map of the
+// found items by their cache keys; missing and expired keys are omitted.
+func (c *GoCache) GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error) {
+	_, op := c.telemetry.start(ctx, "GetMany")
+	defer op.end()
+
+	op.keys(len(cacheKeys))
+
+	items := make(map[string][]byte, len(cacheKeys))
+
+	for _, cacheKey := range cacheKeys {
+		if entry, found := c.getEntry(cacheKey); found {
+			items[cacheKey] = entry.Value
+		}
+	}
+
+	op.lookup(len(cacheKeys), len(items))
+
+	return items, nil
+}
+
+// The `SetMany` function is used to store multiple items in the cache with the TTL from the cache
+// configuration.
+func (c *GoCache) SetMany(ctx context.Context, items map[string][]byte) error {
+	_, op := c.telemetry.start(ctx, "SetMany")
+	defer op.end()
+
+	op.keys(len(items))
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	for cacheKey, item := range items {
+		c.Cache.Set(namespacedKey(c.Config, cacheKey), encodeValue(item), c.Config.TTL)
+	}
 
 	return nil
 }
@@ -0,0 +1,59 @@
+package providers
+
+import (
+	"sync"
+	"time"
+)
+
+// The janitor type runs a maintenance function periodically in a background goroutine until it is
+// stopped. It is used by the providers for work such as removing expired items.
+type janitor struct {
+	stop     chan struct{}
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// The `startJanitor` function starts a janitor calling `run` every `interval`. A non-positive interval
+// does not start a goroutine and returns nil, which is safe to stop.
+func startJanitor(interval time.Duration, run func()) *janitor {
+	if interval <= 0 {
+		return nil
+	}
+
+	j := &janitor{
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(j.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				run()
+			case <-j.stop:
+				return
+			}
+		}
+	}()
+
+	return j
+}
+
+// The `Stop` function stops the janitor and waits for a running maintenance function to return. It can
+// be called multiple times and on a nil janitor.
+func (j *janitor) Stop() {
+	if j == nil {
+		return
+	}
+
+	j.stopOnce.Do(func() {
+		close(j.stop)
+	})
+
+	<-j.done
+}
@@ -0,0 +1,35 @@
+package providers
+
+import (
+	"strings"
+
+	"github.com/wasilak/cachego/config"
+)
+
+// The `namespacedKey` function returns the key under which an item is stored in the backend. Keys of a
+// cache with a `Namespace` are prefixed with the namespace and `config.NamespaceSeparator`, so several
+// caches can share one Redis database or Badger path without their keys colliding. Keys of a cache
+// without a namespace are stored unchanged.
+func namespacedKey(cfg config.Config, cacheKey string) string {
+	if cfg.Namespace == "" {
+		return cacheKey
+	}
+
+	return cfg.Namespace + config.NamespaceSeparator + cacheKey
+}
+
+// The `namespacedKeys` function returns the backend keys of multiple cache keys, in the same order.
+func namespacedKeys(cfg config.Config, cacheKeys []string) []string {
+	keys := make([]string, len(cacheKeys))
+	for i, cacheKey := range cacheKeys {
+		keys[i] = namespacedKey(cfg, cacheKey)
+	}
+
+	return keys
+}
+
+// The `hasNamespacePrefix` function reports whether a backend key belongs to the namespace of the cache
+// and starts with the given prefix.
+func hasNamespacePrefix(cfg config.Config, key, prefix string) bool {
+	return strings.HasPrefix(key, namespacedKey(cfg, prefix))
+}
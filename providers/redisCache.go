@@ -1,6 +1,10 @@
 package providers
 
 import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
 	"time"
 
 	"log/slog"
@@ -11,9 +15,9 @@ import (
 
 // The RedisCache type represents a Redis cache with properties such as the cache client, time-to-live
 // duration, address, database number, tracer, and context.
-// @property Cache - The `Cache` property is a pointer to a Redis client. Redis is an open-source
-// in-memory data structure store that can be used as a cache or a database. The Redis client allows
-// you to interact with a Redis server and perform operations such as storing and retrieving data.
+// @property Cache - The `Cache` property is a Redis universal client. Redis is an open-source
+// in-memory data structure store that can be used as a cache or a database. Depending on the
+// configuration, the client talks to a single node, a Redis Cluster or a Sentinel-managed master.
 // @property TTL - TTL stands for "Time to Live" and it represents the duration for which a cache entry
 // will be considered valid before it expires and is automatically removed from the cache.
 // @property {string} Address - The `Address` property is a string that represents the address of the
@@ -29,10 +33,11 @@ import (
 // RedisCache operations. It allows for cancellation, timeouts, and passing values across API
 // boundaries.
 type RedisCache struct {
-	Cache   *redis.Client
-	Address string
-	DB      int
-	Config  config.Config
+	Cache     redis.UniversalClient
+	Address   string
+	DB        int
+	Config    config.Config
+	telemetry *telemetry
 }
 
 func (c *RedisCache) GetConfig() config.Config {
@@ -41,73 +46,554 @@ func (c *RedisCache) GetConfig() config.Config {
 
 // The `Init` function is a method of the `RedisCache` struct. It initializes the Redis cache by
 // creating a new Redis client and setting it to the `Cache` property of the `RedisCache` struct. The
-// Redis client is created with the provided address and database number. The function returns an error
-// if there is any issue initializing the Redis cache.
-func (c *RedisCache) Init() error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Init")
-	defer span.End()
-
-	c.Cache = redis.NewClient(&redis.Options{
-		Addr: c.Address,
-		DB:   c.DB,
-	})
+// Redis client is created with the provided address and database number, or the `RedisURL`, and the
+// authentication, TLS and pool options from the configuration. Cluster and Sentinel deployments are
+// selected with `RedisAddresses`, `RedisCluster` and `RedisMasterName`. When `PingOnInit` is enabled
+// in the configuration, the server is pinged and the function returns an error if it is unreachable.
+// For a single node without a `Namespace`, the number of keys in the database is reported by the
+// `cache.items` gauge.
+func (c *RedisCache) Init(ctx context.Context) error {
+	telemetry, err := newTelemetry(c.Config)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	c.telemetry = telemetry
+
+	ctx, op := c.telemetry.start(ctx, "Init")
+	defer op.end()
+
+	opts, err := c.options()
+	if err != nil {
+		return op.fail(err)
+	}
+
+	c.Cache = redis.NewUniversalClient(c.universalOptions(opts))
+
+	if c.Config.PingOnInit {
+		if err := c.Cache.Ping(ctx).Err(); err != nil {
+			c.Cache.Close()
+			return op.fail(err)
+		}
+	}
+
+	if c.Config.Namespace != "" || c.isCluster() {
+		return nil
+	}
+
+	return op.fail(c.telemetry.registerGauges(c.Config, c.itemCount, nil))
+}
+
+// The `itemCount` function returns the number of keys in the Redis database using the `DBSIZE`
+// command.
+func (c *RedisCache) itemCount(ctx context.Context) (int64, error) {
+	return c.Cache.DBSize(ctx).Result()
 }
 
 // The `Get` function is a method of the `RedisCache` struct. It is used to retrieve an item from the
 // Redis cache based on the provided cache key.
-func (c *RedisCache) Get(cacheKey string) ([]byte, bool, error) {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Get")
-	defer span.End()
+func (c *RedisCache) Get(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	ctx, op := c.telemetry.start(ctx, "Get")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	entry, found, err := c.getEntry(ctx, op, cacheKey)
+
+	return entry.Value, found, err
+}
+
+// The `GetEntry` function is a method of the `RedisCache` struct. It is used to retrieve an item
+// together with the soft expiry and load duration it was stored with by `SetEntry`. It is used by
+// `GetOrLoad`.
+func (c *RedisCache) GetEntry(ctx context.Context, cacheKey string) (Entry, bool, error) {
+	ctx, op := c.telemetry.start(ctx, "GetEntry")
+	defer op.end()
 
-	item, err := c.Cache.Get(c.Config.CTX, cacheKey).Bytes()
+	op.key(cacheKey)
+
+	return c.getEntry(ctx, op, cacheKey)
+}
+
+// The `SetEntry` function is a method of the `RedisCache` struct. It is used to store an item in an
+// envelope with its soft expiry and load duration, with the given TTL as its hard expiry. `Get` returns
+// the item without the envelope. It is used by `GetOrLoad`.
+func (c *RedisCache) SetEntry(ctx context.Context, cacheKey string, entry Entry, ttl time.Duration) error {
+	ctx, op := c.telemetry.start(ctx, "SetEntry")
+	defer op.end()
+
+	return c.setWithTTL(ctx, op, cacheKey, encodeEntry(entry), ttl)
+}
+
+// The `getEntry` function reads and unwraps an item with the `GET` command. Items with an invalid
+// envelope are reported as missing.
+func (c *RedisCache) getEntry(ctx context.Context, op *operation, cacheKey string) (Entry, bool, error) {
+	item, err := c.Cache.Get(ctx, namespacedKey(c.Config, cacheKey)).Bytes()
 
 	switch {
 	case err == redis.Nil:
 		slog.Info("key does not exist", "key", cacheKey)
-		return item, false, nil
+		op.hit(false)
+		op.lookup(1, 0)
+		return Entry{}, false, nil
 	case err != nil:
-		return item, false, err
+		return Entry{}, false, op.fail(err)
 	}
 
+	entry, err := decodeEntry(item)
+
 	if err != nil || len(item) == 0 {
-		slog.ErrorContext(c.Config.CTX, "Error", slog.Any("message", err))
-		return item, false, err
+		slog.ErrorContext(ctx, "Error", slog.Any("message", err))
+		op.hit(false)
+		op.lookup(1, 0)
+		return Entry{}, false, nil
 	}
 
-	return item, true, nil
+	op.hit(true)
+	op.lookup(1, 1)
+	op.size(len(item))
+
+	return entry, true, nil
 }
 
 // The `Set` function is a method of the `RedisCache` struct. It is used to store an item in the Redis
 // cache with the provided cache key.
-func (c *RedisCache) Set(cacheKey string, item []byte) error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "Set")
-	defer span.End()
+func (c *RedisCache) Set(ctx context.Context, cacheKey string, item []byte) error {
+	ctx, op := c.telemetry.start(ctx, "Set")
+	defer op.end()
+
+	return c.setWithTTL(ctx, op, cacheKey, encodeValue(item), config.DefaultExpiration)
+}
 
-	return c.Cache.Set(c.Config.CTX, cacheKey, item, c.Config.TTL).Err()
+// The `SetWithTTL` function is a method of the `RedisCache` struct. It is used to store an item in the
+// Redis cache with its own time-to-live (TTL) duration. `config.NoExpiration` stores the item without
+// an expiry.
+func (c *RedisCache) SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error {
+	ctx, op := c.telemetry.start(ctx, "SetWithTTL")
+	defer op.end()
+
+	return c.setWithTTL(ctx, op, cacheKey, encodeValue(item), ttl)
+}
+
+// The `setWithTTL` function stores an item, already in its stored form, with its own TTL using the
+// `SET` command. It is shared by the write methods, so each of them records its telemetry once.
+func (c *RedisCache) setWithTTL(ctx context.Context, op *operation, cacheKey string, item []byte, ttl time.Duration) error {
+	op.key(cacheKey)
+	op.size(len(item))
+
+	return op.fail(c.Cache.Set(ctx, namespacedKey(c.Config, cacheKey), item, redisTTL(resolveTTL(ttl, c.Config))).Err())
 }
 
 // The `GetItemTTL` function is a method of the `RedisCache` struct. It is used to retrieve the
 // remaining time-to-live (TTL) duration of an item in the Redis cache based on the provided cache key.
-func (c *RedisCache) GetItemTTL(cacheKey string) (time.Duration, bool, error) {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "GetItemTTL")
-	defer span.End()
+// Items stored without an expiry report `config.NoExpiration`, and items stored with `SetEntry` report
+// the time left until their soft expiry. The TTL and the envelope header are read in a single
+// pipeline of `PTTL` and `GETRANGE`.
+func (c *RedisCache) GetItemTTL(ctx context.Context, cacheKey string) (time.Duration, bool, error) {
+	ctx, op := c.telemetry.start(ctx, "GetItemTTL")
+	defer op.end()
+
+	op.key(cacheKey)
 
-	item, err := c.Cache.TTL(c.Config.CTX, cacheKey).Result()
+	key := namespacedKey(c.Config, cacheKey)
+
+	var pttl *redis.DurationCmd
+	var header *redis.StringCmd
+
+	_, err := c.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pttl = pipe.PTTL(ctx, key)
+		header = pipe.GetRange(ctx, key, 0, envelopeHeaderSize-1)
+		return nil
+	})
 	if err != nil {
-		slog.ErrorContext(c.Config.CTX, "Error", slog.Any("message", err))
-		return item, false, err
+		slog.ErrorContext(ctx, "Error", slog.Any("message", err))
+		return 0, false, op.fail(err)
 	}
 
-	return item, true, nil
+	ttl := pttl.Val()
+
+	// PTTL returns -2 if the key does not exist and -1 if it exists without an expiry
+	switch ttl {
+	case -2:
+		return 0, false, nil
+	case -1:
+		ttl = config.NoExpiration
+	}
+
+	return entryTTL(ttl, []byte(header.Val()), time.Now()), true, nil
 }
 
+// `extendTTLScript` extends the TTL of a key by `ARGV[1]` milliseconds, or removes its expiry if
+// `ARGV[1]` is "persist", in a single atomic step, so concurrent extensions don't lose increments. Keys
+// without an expiry keep it and a shortened TTL never drops below one millisecond, like `extendTTL`.
+var extendTTLScript = redis.NewScript(`
+local ttl = redis.call("PTTL", KEYS[1])
+if ttl < 0 then
+	return 0
+end
+if ARGV[1] == "persist" then
+	return redis.call("PERSIST", KEYS[1])
+end
+return redis.call("PEXPIRE", KEYS[1], math.max(ttl + tonumber(ARGV[1]), 1))
+`)
+
 // The `ExtendTTL` function is a method of the `RedisCache` struct. It is used to extend the
-// time-to-live (TTL) duration of an item in the Redis cache based on the provided cache key.
-func (c *RedisCache) ExtendTTL(cacheKey string, item []byte) error {
-	_, span := c.Config.Tracer.Start(c.Config.CTX, "ExtendTTL")
-	defer span.End()
+// time-to-live (TTL) duration of an item in the Redis cache by the given duration, without rewriting
+// its value. The TTL is read and changed atomically by a Lua script. Extending a missing item is a
+// no-op.
+func (c *RedisCache) ExtendTTL(ctx context.Context, cacheKey string, by time.Duration) error {
+	ctx, op := c.telemetry.start(ctx, "ExtendTTL")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	arg := any(by.Milliseconds())
+	if by == config.NoExpiration {
+		arg = "persist"
+	}
+
+	err := extendTTLScript.Run(ctx, c.Cache, []string{namespacedKey(c.Config, cacheKey)}, arg).Err()
+	if err == redis.Nil {
+		err = nil
+	}
+
+	return op.fail(err)
+}
+
+// The `Touch` function is a method of the `RedisCache` struct. It is used to reset the time-to-live
+// (TTL) duration of an item in the Redis cache to the given duration, without rewriting its value.
+// Touching a missing item is a no-op.
+func (c *RedisCache) Touch(ctx context.Context, cacheKey string, ttl time.Duration) error {
+	ctx, op := c.telemetry.start(ctx, "Touch")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	return op.fail(c.expire(ctx, cacheKey, resolveTTL(ttl, c.Config)))
+}
+
+// The `expire` function sets the TTL of an existing key using `PEXPIRE`, or removes it using `PERSIST`
+// when the TTL is `config.NoExpiration`.
+func (c *RedisCache) expire(ctx context.Context, cacheKey string, ttl time.Duration) error {
+	if ttl == config.NoExpiration {
+		return c.Cache.Persist(ctx, namespacedKey(c.Config, cacheKey)).Err()
+	}
+
+	return c.Cache.PExpire(ctx, namespacedKey(c.Config, cacheKey), ttl).Err()
+}
+
+// The `redisTTL` function converts a cache TTL to the expiration expected by the go-redis `Set`
+// command, where zero means the key never expires.
+func redisTTL(ttl time.Duration) time.Duration {
+	if ttl == config.NoExpiration {
+		return 0
+	}
+
+	return ttl
+}
+
+// The `Delete` function is a method of the `RedisCache` struct. It is used to remove an item from the
+// Redis cache based on the provided cache key using the `DEL` command.
+func (c *RedisCache) Delete(ctx context.Context, cacheKey string) error {
+	ctx, op := c.telemetry.start(ctx, "Delete")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	return op.fail(c.Cache.Del(ctx, namespacedKey(c.Config, cacheKey)).Err())
+}
+
+// The `DeleteMany` function is a method of the `RedisCache` struct. It is used to remove multiple items
+// from the Redis cache with a single `DEL` command. In cluster mode the keys may belong to different
+// hash slots, so a pipeline of `DEL` commands is used instead.
+func (c *RedisCache) DeleteMany(ctx context.Context, cacheKeys []string) error {
+	ctx, op := c.telemetry.start(ctx, "DeleteMany")
+	defer op.end()
+
+	op.keys(len(cacheKeys))
+
+	if len(cacheKeys) == 0 {
+		return nil
+	}
+
+	if !c.isCluster() {
+		return op.fail(c.Cache.Del(ctx, namespacedKeys(c.Config, cacheKeys)...).Err())
+	}
+
+	_, err := c.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, cacheKey := range cacheKeys {
+			pipe.Del(ctx, namespacedKey(c.Config, cacheKey))
+		}
+		return nil
+	})
+
+	return op.fail(err)
+}
+
+// The `Has` function is a method of the `RedisCache` struct. It is used to check if an item exists in
+// the Redis cache using the `EXISTS` command.
+func (c *RedisCache) Has(ctx context.Context, cacheKey string) (bool, error) {
+	ctx, op := c.telemetry.start(ctx, "Has")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	count, err := c.Cache.Exists(ctx, namespacedKey(c.Config, cacheKey)).Result()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error", slog.Any("message", err))
+		return false, op.fail(err)
+	}
+
+	op.hit(count > 0)
+
+	return count > 0, nil
+}
+
+// The `Clear` function is a method of the `RedisCache` struct. It is used to remove all items from the
+// Redis database selected by the `DB` property using the `FLUSHDB` command. In cluster mode the
+// command is sent to every master. A cache with a `Namespace` only removes the keys of its namespace,
+// so caches sharing the database are left untouched.
+func (c *RedisCache) Clear(ctx context.Context) error {
+	ctx, op := c.telemetry.start(ctx, "Clear")
+	defer op.end()
+
+	if c.Config.Namespace != "" {
+		return op.fail(c.clearPrefix(ctx, ""))
+	}
+
+	if cluster, ok := c.Cache.(*redis.ClusterClient); ok {
+		return op.fail(cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return client.FlushDB(ctx).Err()
+		}))
+	}
+
+	return op.fail(c.Cache.FlushDB(ctx).Err())
+}
+
+// `clearPrefixScanCount` is the number of keys requested from each `SCAN` call by `ClearPrefix`.
+const clearPrefixScanCount = 1000
+
+// The `ClearPrefix` function is a method of the `RedisCache` struct. It is used to remove all items
+// whose cache keys start with the given prefix. The keys are found with `SCAN`, so the server is never
+// blocked, and removed with `UNLINK`, which frees their memory in the background. In cluster mode every
+// master is scanned.
+func (c *RedisCache) ClearPrefix(ctx context.Context, prefix string) error {
+	ctx, op := c.telemetry.start(ctx, "ClearPrefix")
+	defer op.end()
+
+	return op.fail(c.clearPrefix(ctx, prefix))
+}
+
+// The `clearPrefix` function unlinks the keys starting with the given prefix on every master.
+func (c *RedisCache) clearPrefix(ctx context.Context, prefix string) error {
+	match := escapeRedisPattern(namespacedKey(c.Config, prefix)) + "*"
+
+	if cluster, ok := c.Cache.(*redis.ClusterClient); ok {
+		return cluster.ForEachMaster(ctx, func(ctx context.Context, client *redis.Client) error {
+			return unlinkMatching(ctx, client, match)
+		})
+	}
+
+	return unlinkMatching(ctx, c.Cache, match)
+}
+
+// The `unlinkMatching` function scans the keys matching the pattern and unlinks them batch by batch.
+// Every key is unlinked with its own command, so the batches never span hash slots.
+func unlinkMatching(ctx context.Context, client redis.Cmdable, match string) error {
+	var cursor uint64
+
+	for {
+		keys, next, err := client.Scan(ctx, cursor, match, clearPrefixScanCount).Result()
+		if err != nil {
+			return err
+		}
+
+		if len(keys) > 0 {
+			_, err = client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+				for _, key := range keys {
+					pipe.Unlink(ctx, key)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		if next == 0 {
+			return nil
+		}
+
+		cursor = next
+	}
+}
+
+// The `escapeRedisPattern` function escapes the glob characters of a key, so it can be used as a
+// literal prefix in a `SCAN MATCH` pattern.
+func escapeRedisPattern(key string) string {
+	var b strings.Builder
+
+	for _, r := range key {
+		switch r {
+		case '*', '?', '[', ']', '\\':
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
+
+// The `Close` function is a method of the `RedisCache` struct. It closes the Redis client and releases
+// all connections of its pool. The cache must not be used after it has been closed.
+func (c *RedisCache) Close(ctx context.Context) error {
+	_, op := c.telemetry.start(ctx, "Close")
+	defer op.end()
+
+	return op.fail(errors.Join(c.telemetry.close(), c.Cache.Close()))
+}
+
+// The `unlockScript` deletes a lock key only if it still holds the token of the caller, so an expired
+// lock that was acquired by someone else is never released by mistake.
+var unlockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// `lockKeyPrefix` starts the keys of the load locks. The locks live outside of the keys of the items,
+// so they never collide with a cache key and are not removed by `ClearPrefix` or the `Clear` of a
+// namespaced cache.
+const lockKeyPrefix = "cachego:lock:"
+
+// The `Lock` function is a method of the `RedisCache` struct. It tries to acquire a lock for the given
+// cache key using `SET NX` with the given TTL. The lock is stored under `lockKeyPrefix` followed by the
+// namespaced cache key. It returns a function releasing the lock and a boolean indicating whether the
+// lock was acquired.
+func (c *RedisCache) Lock(ctx context.Context, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	ctx, op := c.telemetry.start(ctx, "Lock")
+	defer op.end()
+
+	op.key(cacheKey)
+
+	lockKey := lockKeyPrefix + namespacedKey(c.Config, cacheKey)
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, false, err
+	}
+
+	acquired, err := c.Cache.SetNX(ctx, lockKey, token, ttl).Result()
+	if err != nil || !acquired {
+		return nil, false, op.fail(err)
+	}
+
+	unlock := func(ctx context.Context) error {
+		return unlockScript.Run(ctx, c.Cache, []string{lockKey}, token).Err()
+	}
+
+	return unlock, true, nil
+}
+
+// The `GetMany` function is a method of the `RedisCache` struct. It is used to retrieve multiple items
+// from the Redis cache with a single `MGET` command. In cluster mode the keys may belong to different
+// hash slots, so a pipeline of `GET` commands is used instead, which go-redis routes to the right
+// nodes. It returns a map of the found items by their cache keys; missing keys are omitted.
+func (c *RedisCache) GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error) {
+	ctx, op := c.telemetry.start(ctx, "GetMany")
+	defer op.end()
+
+	op.keys(len(cacheKeys))
+
+	items := make(map[string][]byte, len(cacheKeys))
+
+	if len(cacheKeys) == 0 {
+		return items, nil
+	}
+
+	if c.isCluster() {
+		if err := c.pipelinedGetMany(ctx, cacheKeys, items); err != nil {
+			return items, op.fail(err)
+		}
+
+		op.lookup(len(cacheKeys), len(items))
+
+		return items, nil
+	}
+
+	values, err := c.Cache.MGet(ctx, namespacedKeys(c.Config, cacheKeys)...).Result()
+	if err != nil {
+		slog.ErrorContext(ctx, "Error", slog.Any("message", err))
+		return nil, op.fail(err)
+	}
+
+	for i, value := range values {
+		if value, ok := value.(string); ok {
+			if item, ok := unwrapValue([]byte(value)); ok {
+				items[cacheKeys[i]] = item
+			}
+		}
+	}
+
+	op.lookup(len(cacheKeys), len(items))
+
+	return items, nil
+}
+
+// The `pipelinedGetMany` function retrieves multiple items with a pipeline of `GET` commands and adds
+// the found ones to the items map.
+func (c *RedisCache) pipelinedGetMany(ctx context.Context, cacheKeys []string, items map[string][]byte) error {
+	cmds := make([]*redis.StringCmd, len(cacheKeys))
+
+	_, err := c.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, cacheKey := range cacheKeys {
+			cmds[i] = pipe.Get(ctx, namespacedKey(c.Config, cacheKey))
+		}
+		return nil
+	})
+	if err != nil && err != redis.Nil {
+		slog.ErrorContext(ctx, "Error", slog.Any("message", err))
+		return err
+	}
+
+	for i, cmd := range cmds {
+		if stored, err := cmd.Bytes(); err == nil {
+			if item, ok := unwrapValue(stored); ok {
+				items[cacheKeys[i]] = item
+			}
+		}
+	}
+
+	return nil
+}
+
+// The `isCluster` function reports whether the client talks to a Redis Cluster, where multi-key
+// commands must not span hash slots.
+func (c *RedisCache) isCluster() bool {
+	_, ok := c.Cache.(*redis.ClusterClient)
+	return ok
+}
+
+// The `SetMany` function is a method of the `RedisCache` struct. It is used to store multiple items in
+// the Redis cache with the TTL from the cache configuration. The `SET` commands are sent in a single
+// pipeline.
+func (c *RedisCache) SetMany(ctx context.Context, items map[string][]byte) error {
+	ctx, op := c.telemetry.start(ctx, "SetMany")
+	defer op.end()
+
+	op.keys(len(items))
+
+	if len(items) == 0 {
+		return nil
+	}
+
+	_, err := c.Cache.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for cacheKey, item := range items {
+			pipe.Set(ctx, namespacedKey(c.Config, cacheKey), encodeValue(item), redisTTL(c.Config.TTL))
+		}
+		return nil
+	})
 
-	return c.Cache.Expire(c.Config.CTX, cacheKey, c.Config.TTL).Err()
+	return op.fail(err)
 }
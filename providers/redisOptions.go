@@ -0,0 +1,160 @@
+package providers
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// The `options` function builds the go-redis client options from the cache configuration. The
+// connection is taken from `RedisURL` if it is set, from `Address` and `DB` otherwise, and the other
+// Redis properties are applied on top. Zero values keep the go-redis defaults.
+func (c *RedisCache) options() (*redis.Options, error) {
+	opts := &redis.Options{
+		Addr: c.Address,
+		DB:   c.DB,
+	}
+
+	if c.Config.RedisURL != "" {
+		var err error
+
+		opts, err = redis.ParseURL(c.Config.RedisURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redis URL: %w", err)
+		}
+	}
+
+	if c.Config.RedisUsername != "" {
+		opts.Username = c.Config.RedisUsername
+	}
+
+	if c.Config.RedisPassword != "" {
+		opts.Password = c.Config.RedisPassword
+	}
+
+	if c.Config.RedisPoolSize != 0 {
+		opts.PoolSize = c.Config.RedisPoolSize
+	}
+
+	if c.Config.RedisMinIdleConns != 0 {
+		opts.MinIdleConns = c.Config.RedisMinIdleConns
+	}
+
+	if c.Config.RedisMaxRetries != 0 {
+		opts.MaxRetries = c.Config.RedisMaxRetries
+	}
+
+	if c.Config.RedisDialTimeout != 0 {
+		opts.DialTimeout = c.Config.RedisDialTimeout
+	}
+
+	if c.Config.RedisReadTimeout != 0 {
+		opts.ReadTimeout = c.Config.RedisReadTimeout
+	}
+
+	if c.Config.RedisWriteTimeout != 0 {
+		opts.WriteTimeout = c.Config.RedisWriteTimeout
+	}
+
+	tlsConfig, err := c.tlsConfig(opts.TLSConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	opts.TLSConfig = tlsConfig
+
+	return opts, nil
+}
+
+// The `universalOptions` function converts the client options into options for a go-redis universal
+// client. The client is a Sentinel-backed failover client when `RedisMasterName` is set, a cluster
+// client when `RedisAddresses` has two or more addresses or `RedisCluster` is set, and a single-node
+// client otherwise.
+func (c *RedisCache) universalOptions(opts *redis.Options) *redis.UniversalOptions {
+	addrs := []string{opts.Addr}
+	if len(c.Config.RedisAddresses) > 0 {
+		addrs = c.Config.RedisAddresses
+	}
+
+	return &redis.UniversalOptions{
+		Addrs:            addrs,
+		ClientName:       opts.ClientName,
+		DB:               opts.DB,
+		Protocol:         opts.Protocol,
+		Username:         opts.Username,
+		Password:         opts.Password,
+		SentinelUsername: c.Config.RedisSentinelUsername,
+		SentinelPassword: c.Config.RedisSentinelPassword,
+		MaxRetries:       opts.MaxRetries,
+		MinRetryBackoff:  opts.MinRetryBackoff,
+		MaxRetryBackoff:  opts.MaxRetryBackoff,
+		DialTimeout:      opts.DialTimeout,
+		ReadTimeout:      opts.ReadTimeout,
+		WriteTimeout:     opts.WriteTimeout,
+		PoolFIFO:         opts.PoolFIFO,
+		PoolSize:         opts.PoolSize,
+		PoolTimeout:      opts.PoolTimeout,
+		MinIdleConns:     opts.MinIdleConns,
+		MaxIdleConns:     opts.MaxIdleConns,
+		MaxActiveConns:   opts.MaxActiveConns,
+		ConnMaxIdleTime:  opts.ConnMaxIdleTime,
+		ConnMaxLifetime:  opts.ConnMaxLifetime,
+		TLSConfig:        opts.TLSConfig,
+		MasterName:       c.Config.RedisMasterName,
+		IsClusterMode:    c.Config.RedisCluster,
+	}
+}
+
+// The `tlsConfig` function returns the TLS configuration for the Redis connection, based on the one
+// parsed from a `rediss://` URL (if any) and the `RedisTLS*` properties. It returns nil if TLS is not
+// enabled.
+func (c *RedisCache) tlsConfig(base *tls.Config) (*tls.Config, error) {
+	cfg := c.Config
+
+	enabled := base != nil || cfg.RedisTLS || cfg.RedisTLSCAFile != "" || cfg.RedisTLSCertFile != "" ||
+		cfg.RedisTLSKeyFile != "" || cfg.RedisTLSServerName != "" || cfg.RedisTLSInsecureSkipVerify
+	if !enabled {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if base != nil {
+		tlsConfig = base.Clone()
+	}
+
+	if cfg.RedisTLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.RedisTLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read redis TLS CA file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in redis TLS CA file %q", cfg.RedisTLSCAFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.RedisTLSCertFile != "" || cfg.RedisTLSKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.RedisTLSCertFile, cfg.RedisTLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load redis TLS client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.RedisTLSServerName != "" {
+		tlsConfig.ServerName = cfg.RedisTLSServerName
+	}
+
+	if cfg.RedisTLSInsecureSkipVerify {
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	return tlsConfig, nil
+}
@@ -0,0 +1,242 @@
+package providers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// `meterName` is the instrumentation scope of the meter used when `Config.Meter` is not set.
+const meterName = "github.com/wasilak/cachego"
+
+// The `telemetry` type holds the OpenTelemetry instruments of a cache. Every measurement is labeled
+// with the provider type (`cache.provider`) and the namespace (`cache.namespace`) of the cache.
+// @property tracer - The `tracer` property is the tracer from the cache configuration.
+// @property hashKeys - The `hashKeys` property enables hashing of the `cache.key` span attribute.
+// @property attrs - The `attrs` property holds the provider and namespace attributes.
+// @property hits - The `hits` property counts the keys found by `Get` and `GetMany`.
+// @property misses - The `misses` property counts the keys not found by `Get` and `GetMany`.
+// @property errors - The `errors` property counts the failed operations.
+// @property duration - The `duration` property records the latency of every operation.
+// @property registration - The `registration` property is the callback of the item count and size
+// gauges, it is unregistered on `close`.
+type telemetry struct {
+	tracer       trace.Tracer
+	hashKeys     bool
+	attrs        attribute.Set
+	hits         metric.Int64Counter
+	misses       metric.Int64Counter
+	errors       metric.Int64Counter
+	duration     metric.Float64Histogram
+	registration metric.Registration
+}
+
+// The `newTelemetry` function creates the instruments of a cache from its configuration. It falls back
+// to the global tracer and meter providers if the configuration doesn't set them.
+func newTelemetry(cfg config.Config) (*telemetry, error) {
+	t := &telemetry{
+		tracer:   cfg.Tracer,
+		hashKeys: cfg.TelemetryHashKeys,
+		attrs: attribute.NewSet(
+			attribute.String("cache.provider", cfg.Type),
+			attribute.String("cache.namespace", cfg.Namespace),
+		),
+	}
+
+	if t.tracer == nil {
+		t.tracer = otel.Tracer(cfg.Type)
+	}
+
+	meter := cfg.Meter
+	if meter == nil {
+		meter = otel.Meter(meterName)
+	}
+
+	var err, errs error
+
+	t.hits, err = meter.Int64Counter("cache.hits",
+		metric.WithDescription("Number of cache keys found."),
+		metric.WithUnit("{hit}"))
+	errs = errors.Join(errs, err)
+
+	t.misses, err = meter.Int64Counter("cache.misses",
+		metric.WithDescription("Number of cache keys not found."),
+		metric.WithUnit("{miss}"))
+	errs = errors.Join(errs, err)
+
+	t.errors, err = meter.Int64Counter("cache.errors",
+		metric.WithDescription("Number of failed cache operations."),
+		metric.WithUnit("{error}"))
+	errs = errors.Join(errs, err)
+
+	t.duration, err = meter.Float64Histogram("cache.operation.duration",
+		metric.WithDescription("Duration of cache operations."),
+		metric.WithUnit("s"))
+	errs = errors.Join(errs, err)
+
+	return t, errs
+}
+
+// The `registerGauges` function registers the `cache.items` and `cache.size` gauges with the meter from
+// the configuration. The callbacks report the number of items and the size in bytes of the cache, a
+// nil callback means the backend doesn't support the measurement. Failing callbacks are skipped.
+func (t *telemetry) registerGauges(cfg config.Config, items, size func(context.Context) (int64, error)) error {
+	meter := cfg.Meter
+	if meter == nil {
+		meter = otel.Meter(meterName)
+	}
+
+	var observables []metric.Observable
+	var itemsGauge, sizeGauge metric.Int64ObservableGauge
+
+	if items != nil {
+		gauge, err := meter.Int64ObservableGauge("cache.items",
+			metric.WithDescription("Number of items in the cache."),
+			metric.WithUnit("{item}"))
+		if err != nil {
+			return err
+		}
+
+		itemsGauge = gauge
+		observables = append(observables, gauge)
+	}
+
+	if size != nil {
+		gauge, err := meter.Int64ObservableGauge("cache.size",
+			metric.WithDescription("Size of the cache."),
+			metric.WithUnit("By"))
+		if err != nil {
+			return err
+		}
+
+		sizeGauge = gauge
+		observables = append(observables, gauge)
+	}
+
+	if len(observables) == 0 {
+		return nil
+	}
+
+	registration, err := meter.RegisterCallback(func(ctx context.Context, o metric.Observer) error {
+		if items != nil {
+			if n, err := items(ctx); err == nil {
+				o.ObserveInt64(itemsGauge, n, metric.WithAttributeSet(t.attrs))
+			}
+		}
+
+		if size != nil {
+			if n, err := size(ctx); err == nil {
+				o.ObserveInt64(sizeGauge, n, metric.WithAttributeSet(t.attrs))
+			}
+		}
+
+		return nil
+	}, observables...)
+	if err != nil {
+		return err
+	}
+
+	t.registration = registration
+
+	return nil
+}
+
+// The `close` function unregisters the gauge callback, so a closed cache is no longer observed.
+func (t *telemetry) close() error {
+	if t.registration == nil {
+		return nil
+	}
+
+	return t.registration.Unregister()
+}
+
+// The `operation` type represents a single cache operation in flight, with the context and span it
+// was started with and its start time.
+type operation struct {
+	t     *telemetry
+	ctx   context.Context
+	name  string
+	span  trace.Span
+	start time.Time
+}
+
+// The `start` function starts a span for the operation with the given name and returns the context
+// of the span and the operation. The operation must be finished with `end`.
+func (t *telemetry) start(ctx context.Context, name string) (context.Context, *operation) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(t.attrs.ToSlice()...))
+
+	return ctx, &operation{t: t, ctx: ctx, name: name, span: span, start: time.Now()}
+}
+
+// The `key` function records the cache key of the operation in the `cache.key` span attribute, hashed
+// if `TelemetryHashKeys` is set.
+func (o *operation) key(cacheKey string) {
+	if o.t.hashKeys {
+		sum := sha256.Sum256([]byte(cacheKey))
+		cacheKey = hex.EncodeToString(sum[:])
+	}
+
+	o.span.SetAttributes(attribute.String("cache.key", cacheKey))
+}
+
+// The `keys` function records the number of keys of a batch operation in the `cache.keys` span
+// attribute.
+func (o *operation) keys(n int) {
+	o.span.SetAttributes(attribute.Int("cache.keys", n))
+}
+
+// The `size` function records the size of the value read or written in the `cache.value.size` span
+// attribute.
+func (o *operation) size(n int) {
+	o.span.SetAttributes(attribute.Int("cache.value.size", n))
+}
+
+// The `hit` function records in the `cache.hit` span attribute whether the key was found, without
+// counting it.
+func (o *operation) hit(found bool) {
+	o.span.SetAttributes(attribute.Bool("cache.hit", found))
+}
+
+// The `lookup` function counts a lookup of `found` hits out of `requested` keys in the `cache.hits`
+// and `cache.misses` counters.
+func (o *operation) lookup(requested, found int) {
+	if found > 0 {
+		o.t.hits.Add(o.ctx, int64(found), metric.WithAttributeSet(o.t.attrs))
+	}
+
+	if requested > found {
+		o.t.misses.Add(o.ctx, int64(requested-found), metric.WithAttributeSet(o.t.attrs))
+	}
+}
+
+// The `fail` function records a non-nil error on the span and counts it in `cache.errors`. The error is
+// returned unchanged, so it can wrap the returned value, e.g. `return op.fail(err)`.
+func (o *operation) fail(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	o.span.RecordError(err)
+	o.span.SetStatus(codes.Error, err.Error())
+	o.t.errors.Add(o.ctx, 1, metric.WithAttributeSet(o.t.attrs),
+		metric.WithAttributes(attribute.String("cache.operation", o.name)))
+
+	return err
+}
+
+// The `end` function records the latency of the operation in `cache.operation.duration` and ends its
+// span.
+func (o *operation) end() {
+	o.t.duration.Record(o.ctx, time.Since(o.start).Seconds(), metric.WithAttributeSet(o.t.attrs),
+		metric.WithAttributes(attribute.String("cache.operation", o.name)))
+	o.span.End()
+}
@@ -0,0 +1,108 @@
+package providers
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+// recordingTracer records the names of the spans it starts.
+type recordingTracer struct {
+	noop.Tracer
+	mu    sync.Mutex
+	spans []string
+}
+
+func (r *recordingTracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	r.mu.Lock()
+	r.spans = append(r.spans, name)
+	r.mu.Unlock()
+
+	return r.Tracer.Start(ctx, name, opts...)
+}
+
+// record returns the names of the spans started while running fn.
+func (r *recordingTracer) record(fn func()) []string {
+	r.mu.Lock()
+	r.spans = nil
+	r.mu.Unlock()
+
+	fn()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.spans
+}
+
+// writer is implemented by the providers whose write operations are tested.
+type writer interface {
+	Set(ctx context.Context, cacheKey string, item []byte) error
+	SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error
+	SetEntry(ctx context.Context, cacheKey string, entry Entry, ttl time.Duration) error
+	Delete(ctx context.Context, cacheKey string) error
+	DeleteMany(ctx context.Context, cacheKeys []string) error
+	Clear(ctx context.Context) error
+	ClearPrefix(ctx context.Context, prefix string) error
+}
+
+func TestOperationsRecordOneSpan(t *testing.T) {
+	ctx := context.Background()
+
+	caches := map[string]func(t *testing.T, tracer trace.Tracer) writer{
+		"memory": func(t *testing.T, tracer trace.Tracer) writer {
+			c := &GoCache{Config: config.Config{Type: "memory", TTL: time.Minute, Namespace: "ns", Tracer: tracer}}
+			if err := c.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() { c.Close(ctx) })
+
+			return c
+		},
+		"badger": func(t *testing.T, tracer trace.Tracer) writer {
+			c := &BadgerCache{Config: config.Config{Type: "badger", TTL: time.Minute, Namespace: "ns", BadgerInMemory: true, Tracer: tracer}}
+			if err := c.Init(ctx); err != nil {
+				t.Fatal(err)
+			}
+
+			t.Cleanup(func() { c.Close(ctx) })
+
+			return c
+		},
+	}
+
+	tests := []struct {
+		name string
+		run  func(c writer)
+	}{
+		{"Set", func(c writer) { c.Set(ctx, "k", []byte("v")) }},
+		{"SetWithTTL", func(c writer) { c.SetWithTTL(ctx, "k", []byte("v"), time.Minute) }},
+		{"SetEntry", func(c writer) {
+			c.SetEntry(ctx, "k", Entry{Value: []byte("v"), SoftExpiry: time.Now().Add(time.Minute)}, time.Minute)
+		}},
+		{"Delete", func(c writer) { c.Delete(ctx, "k") }},
+		{"DeleteMany", func(c writer) { c.DeleteMany(ctx, []string{"k"}) }},
+		{"Clear", func(c writer) { c.Clear(ctx) }},
+		{"ClearPrefix", func(c writer) { c.ClearPrefix(ctx, "k") }},
+	}
+
+	for provider, newCache := range caches {
+		t.Run(provider, func(t *testing.T) {
+			tracer := &recordingTracer{}
+			c := newCache(t, tracer)
+
+			for _, tt := range tests {
+				if spans := tracer.record(func() { tt.run(c) }); !slices.Equal(spans, []string{tt.name}) {
+					t.Errorf("%s: spans = %v, want [%s]", tt.name, spans, tt.name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package providers
+
+import (
+	"time"
+
+	"github.com/wasilak/cachego/config"
+)
+
+// The `resolveTTL` function returns the TTL that should be used for an item. The
+// `config.DefaultExpiration` value is replaced with the TTL from the cache configuration, any other
+// value (including `config.NoExpiration`) is returned unchanged.
+func resolveTTL(ttl time.Duration, cfg config.Config) time.Duration {
+	if ttl == config.DefaultExpiration {
+		return cfg.TTL
+	}
+
+	return ttl
+}
+
+// The `extendTTL` function returns the TTL of an item with `remaining` time left after extending it by
+// `by`. Items without an expiry keep it, and extending by `config.NoExpiration` removes the expiry. A
+// negative `by` shortens the TTL, but never below one millisecond, so the item expires instead of
+// becoming persistent.
+func extendTTL(remaining, by time.Duration) time.Duration {
+	if remaining == config.NoExpiration || by == config.NoExpiration {
+		return config.NoExpiration
+	}
+
+	if remaining+by < time.Millisecond {
+		return time.Millisecond
+	}
+
+	return remaining + by
+}
@@ -0,0 +1,191 @@
+package cachego
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/wasilak/cachego/providers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// `defaultRefreshConcurrency` is the number of concurrent refreshes when
+// `RefreshAheadConfig.MaxConcurrent` is not set.
+const defaultRefreshConcurrency = 4
+
+// `defaultRefreshWindowRatio` is the share of the cache TTL used as refresh window when
+// `RefreshAheadConfig.Window` is not set.
+const defaultRefreshWindowRatio = 5
+
+// The `RefreshAheadConfig` type represents the configuration of a `RefreshAhead` cache.
+// @property Window - The `Window` property is how long before its expiry a registered key is refreshed
+// when it is read. It defaults to a fifth of the cache TTL.
+// @property {int} MaxConcurrent - The `MaxConcurrent` property is the maximum number of refreshes
+// running at the same time. Reads that would start another refresh skip it; the key is refreshed by a
+// later read or loaded on a miss. It defaults to 4.
+type RefreshAheadConfig struct {
+	Window        time.Duration
+	MaxConcurrent int
+}
+
+// The `RefreshAhead` type is a decorator around any `CacheInterface` that keeps hot keys from ever
+// expiring under load. Keys are registered with the loader for their value; whenever a registered key
+// is read with `Get` within `Window` of its expiry, according to `GetItemTTL`, the loader is re-run in
+// the background and the fresh value is stored with the regular TTL. For items stored with stale
+// serving or early expiration enabled, `GetItemTTL` reports the time left until the soft expiry, so
+// they are refreshed before they turn stale. Refreshes of the same key are
+// deduplicated, share the load path of `GetOrLoad` (including `LoadLock`), and are traced as
+// `RefreshAhead` spans with the outcome in the `cache.refresh.outcome` attribute. Whether a read
+// started a refresh is recorded in the `cache.refresh` attribute of its `Get` span.
+type RefreshAhead struct {
+	CacheInterface
+	window     time.Duration
+	slots      chan struct{}
+	tracer     trace.Tracer
+	mu         sync.RWMutex
+	loaders    map[string]LoaderFunc
+	refreshing sync.Map
+	wg         sync.WaitGroup
+}
+
+// The `NewRefreshAhead` function creates a `RefreshAhead` cache around the given cache. Closing it
+// waits for the running refreshes and closes the underlying cache.
+func NewRefreshAhead(cache CacheInterface, cfg RefreshAheadConfig) *RefreshAhead {
+	if cfg.Window <= 0 {
+		cfg.Window = cache.GetConfig().TTL / defaultRefreshWindowRatio
+	}
+
+	if cfg.MaxConcurrent <= 0 {
+		cfg.MaxConcurrent = defaultRefreshConcurrency
+	}
+
+	return &RefreshAhead{
+		CacheInterface: cache,
+		window:         cfg.Window,
+		slots:          make(chan struct{}, cfg.MaxConcurrent),
+		tracer:         otel.Tracer("RefreshAhead"),
+		loaders:        map[string]LoaderFunc{},
+	}
+}
+
+// The `Register` function registers the loader used to refresh the given key. Registering a key again
+// replaces its loader.
+func (c *RefreshAhead) Register(cacheKey string, loader LoaderFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.loaders[cacheKey] = loader
+}
+
+// The `Unregister` function stops refreshing the given key. The item itself is left in the cache.
+func (c *RefreshAhead) Unregister(cacheKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.loaders, cacheKey)
+}
+
+// The `Get` function retrieves an item from the underlying cache and, if the key is registered and
+// about to expire, starts refreshing it in the background. The current item is returned without
+// waiting for the refresh.
+func (c *RefreshAhead) Get(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	ctx, span := c.tracer.Start(ctx, "Get")
+	defer span.End()
+
+	item, found, err := c.CacheInterface.Get(ctx, cacheKey)
+	if err != nil || !found {
+		return item, found, err
+	}
+
+	c.mu.RLock()
+	loader, ok := c.loaders[cacheKey]
+	c.mu.RUnlock()
+
+	if ok {
+		span.SetAttributes(attribute.String("cache.refresh", c.maybeRefresh(ctx, cacheKey, loader)))
+	}
+
+	return item, true, nil
+}
+
+// The `maybeRefresh` function starts a background refresh of the key if its remaining TTL is within the
+// window, no refresh of it is running yet and a refresh slot is free. It returns what it did: "none",
+// "started", "running" or "saturated".
+func (c *RefreshAhead) maybeRefresh(ctx context.Context, cacheKey string, loader LoaderFunc) string {
+	ttl, found, err := c.CacheInterface.GetItemTTL(ctx, cacheKey)
+	if err != nil || !found || ttl == NoExpiration || ttl > c.window {
+		return "none"
+	}
+
+	if _, running := c.refreshing.LoadOrStore(cacheKey, struct{}{}); running {
+		return "running"
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	default:
+		c.refreshing.Delete(cacheKey)
+		return "saturated"
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer func() { <-c.slots }()
+		defer c.refreshing.Delete(cacheKey)
+
+		c.refresh(context.WithoutCancel(ctx), cacheKey, ttl, loader)
+	}()
+
+	return "started"
+}
+
+// The `refresh` function reloads a key through the load path of `GetOrLoad` and records the outcome on
+// its span.
+func (c *RefreshAhead) refresh(ctx context.Context, cacheKey string, remaining time.Duration, loader LoaderFunc) {
+	ctx, span := c.tracer.Start(ctx, "RefreshAhead", trace.WithAttributes(
+		attribute.String("cache.key", cacheKey),
+		attribute.Int64("cache.ttl_remaining_ms", remaining.Milliseconds()),
+	))
+	defer span.End()
+
+	res := <-refresh(ctx, c, cacheKey, loader)
+	if res.Err != nil {
+		span.RecordError(res.Err)
+		span.SetStatus(codes.Error, res.Err.Error())
+		span.SetAttributes(attribute.String("cache.refresh.outcome", "error"))
+		return
+	}
+
+	span.SetAttributes(
+		attribute.String("cache.refresh.outcome", "refreshed"),
+		attribute.Bool("cache.refresh.shared", res.Shared),
+	)
+}
+
+// The `GetEntry` function retrieves an item from the underlying cache with the metadata stored by
+// `GetOrLoad`. It doesn't start a refresh, `GetOrLoad` reloads items itself.
+func (c *RefreshAhead) GetEntry(ctx context.Context, cacheKey string) (providers.Entry, bool, error) {
+	return getEntry(ctx, c.CacheInterface, cacheKey)
+}
+
+// The `SetEntry` function stores an item with the metadata used by `GetOrLoad` in the underlying
+// cache, so refreshed items keep their soft expiry.
+func (c *RefreshAhead) SetEntry(ctx context.Context, cacheKey string, entry providers.Entry, ttl time.Duration) error {
+	return setEntry(ctx, c.CacheInterface, cacheKey, entry, ttl)
+}
+
+// The `Lock` function acquires the distributed lock of the underlying cache used by `GetOrLoad`.
+func (c *RefreshAhead) Lock(ctx context.Context, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	return lockThrough(ctx, c.CacheInterface, cacheKey, ttl)
+}
+
+// The `Close` function waits for the running refreshes and closes the underlying cache.
+func (c *RefreshAhead) Close(ctx context.Context) error {
+	c.wg.Wait()
+
+	return c.CacheInterface.Close(ctx)
+}
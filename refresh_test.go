@@ -0,0 +1,53 @@
+package cachego
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshAheadWithStaleEntries(t *testing.T) {
+	ctx := context.Background()
+	cache := NewRefreshAhead(newTestCache(t, staleConfig("2s")), RefreshAheadConfig{Window: time.Second})
+
+	var loads atomic.Int32
+	loader := func(context.Context) ([]byte, error) {
+		loads.Add(1)
+		return []byte("v"), nil
+	}
+
+	cache.Register("k", loader)
+
+	if _, err := GetOrLoad(ctx, cache, "k", loader); err != nil {
+		t.Fatal(err)
+	}
+
+	if ttl, _, _ := cache.GetItemTTL(ctx, "k"); ttl > 2*time.Second {
+		t.Fatalf("GetItemTTL() = %v, want at most the TTL without the stale window", ttl)
+	}
+
+	if item, found, err := cache.Get(ctx, "k"); err != nil || !found || string(item) != "v" {
+		t.Fatalf("Get() = %q, %v, %v, want %q, true, nil", item, found, err, "v")
+	}
+
+	if n := loads.Load(); n != 1 {
+		t.Fatalf("loads outside of the window = %d, want 1", n)
+	}
+
+	time.Sleep(1100 * time.Millisecond)
+
+	if item, found, err := cache.Get(ctx, "k"); err != nil || !found || string(item) != "v" {
+		t.Fatalf("Get() within the window = %q, %v, %v, want %q, true, nil", item, found, err, "v")
+	}
+
+	cache.wg.Wait()
+
+	if n := loads.Load(); n != 2 {
+		t.Errorf("loads after a read within the window = %d, want 2", n)
+	}
+
+	if entry, found, _ := getEntry(ctx, cache, "k"); !found || time.Until(entry.SoftExpiry) < time.Second {
+		t.Errorf("refreshed entry = %+v, %v, want a new soft expiry", entry, found)
+	}
+}
@@ -0,0 +1,94 @@
+package cachego
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/wasilak/cachego/config"
+	"github.com/wasilak/cachego/providers"
+)
+
+// The `ProviderFactory` type is a function that creates a cache provider for the given configuration.
+// The configuration passed to the factory already has the defaults, the parsed `TTL` and a `Tracer`
+// applied. The returned cache must not be initialized yet, `New` calls its `Init` method.
+type ProviderFactory func(cfg config.Config) (CacheInterface, error)
+
+var (
+	providersMu       sync.RWMutex
+	providerFactories = map[string]ProviderFactory{}
+)
+
+// The `init` function registers the built-in memory, badger (also available as file) and redis
+// providers.
+func init() {
+	RegisterProvider("memory", newGoCache)
+	RegisterProvider("file", newBadgerCache)
+	RegisterProvider("badger", newBadgerCache)
+	RegisterProvider("redis", newRedisCache)
+}
+
+// The `RegisterProvider` function makes a cache provider available under the given type name, so it can
+// be selected with `Config.Type` in `New` and `CacheInit`. It is meant to be called from the `init`
+// function of the package implementing the provider. If `RegisterProvider` is called twice with the
+// same name or if the factory is nil, it panics.
+func RegisterProvider(name string, factory ProviderFactory) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	if factory == nil {
+		panic("cachego: RegisterProvider factory is nil")
+	}
+
+	if _, exists := providerFactories[name]; exists {
+		panic(fmt.Sprintf("cachego: RegisterProvider called twice for provider %q", name))
+	}
+
+	providerFactories[name] = factory
+}
+
+// The `Providers` function returns a sorted list of the names of the registered providers.
+func Providers() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := make([]string, 0, len(providerFactories))
+	for name := range providerFactories {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// The `lookupProvider` function returns the factory registered under the given name.
+func lookupProvider(name string) (ProviderFactory, bool) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	factory, ok := providerFactories[name]
+
+	return factory, ok
+}
+
+func newGoCache(cfg config.Config) (CacheInterface, error) {
+	return &providers.GoCache{
+		Config: cfg,
+	}, nil
+}
+
+func newBadgerCache(cfg config.Config) (CacheInterface, error) {
+	return &providers.BadgerCache{
+		Path:   cfg.Path,
+		Config: cfg,
+	}, nil
+}
+
+func newRedisCache(cfg config.Config) (CacheInterface, error) {
+	return &providers.RedisCache{
+		Address: cfg.RedisHost,
+		DB:      cfg.RedisDB,
+		Config:  cfg,
+	}, nil
+}
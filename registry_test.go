@@ -0,0 +1,118 @@
+package cachego
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/wasilak/cachego/config"
+	"github.com/wasilak/cachego/providers"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestNewTracer(t *testing.T) {
+	custom := noop.NewTracerProvider().Tracer("custom")
+
+	tests := []struct {
+		name   string
+		tracer trace.Tracer
+		want   trace.Tracer
+	}{
+		{name: "custom tracer is kept", tracer: custom, want: custom},
+		{name: "default tracer", tracer: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache, err := New(context.Background(), config.Config{Type: "memory", Tracer: tt.tracer})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer cache.Close(context.Background())
+
+			got := cache.GetConfig().Tracer
+			if got == nil || (tt.want != nil && got != tt.want) {
+				t.Errorf("GetConfig().Tracer = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	failing := errors.New("init failed")
+
+	RegisterProvider("registry-test-failing", func(cfg config.Config) (CacheInterface, error) {
+		return nil, failing
+	})
+
+	tests := []struct {
+		name    string
+		typ     string
+		wantErr error
+	}{
+		{name: "unknown type", typ: "no-such-provider", wantErr: ErrUnknownProvider},
+		{name: "failing factory", typ: "registry-test-failing", wantErr: failing},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(context.Background(), config.Config{Type: tt.typ})
+
+			var initErr *InitError
+			if !errors.As(err, &initErr) || initErr.Provider != tt.typ {
+				t.Fatalf("New() error = %v, want an *InitError for %q", err, tt.typ)
+			}
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("New() error = %v, want it to wrap %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestRegisterProvider(t *testing.T) {
+	var got config.Config
+
+	RegisterProvider("registry-test", func(cfg config.Config) (CacheInterface, error) {
+		got = cfg
+		return &providers.GoCache{Config: cfg}, nil
+	})
+
+	if !slices.Contains(Providers(), "registry-test") {
+		t.Errorf("Providers() = %v, want it to contain %q", Providers(), "registry-test")
+	}
+
+	cache, err := New(context.Background(), config.Config{Type: "registry-test", Expiration: "5m"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close(context.Background())
+
+	if got.TTL.Minutes() != 5 || got.Tracer == nil {
+		t.Errorf("factory config = TTL %v, tracer %v, want the parsed TTL and a tracer", got.TTL, got.Tracer)
+	}
+
+	tests := map[string]ProviderFactory{
+		"registered twice": func(config.Config) (CacheInterface, error) { return nil, nil },
+		"nil factory":      nil,
+	}
+
+	for name, factory := range tests {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("RegisterProvider() did not panic")
+				}
+			}()
+
+			providerName := "registry-test"
+			if factory == nil {
+				providerName = "registry-test-nil"
+			}
+
+			RegisterProvider(providerName, factory)
+		})
+	}
+}
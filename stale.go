@@ -0,0 +1,84 @@
+package cachego
+
+import (
+	"context"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"github.com/wasilak/cachego/providers"
+)
+
+// Items loaded by `GetOrLoad` with `StaleWhileRevalidate`, `StaleIfError` or `EarlyExpirationBeta`
+// enabled are stored as a `providers.Entry` carrying their soft expiry, the end of their regular TTL,
+// and the time their loader took. The item itself is stored with a hard TTL extended by the stale
+// window, so it can still be served after the soft expiry. The providers keep the entry metadata out of
+// the values returned by `Get` and `GetMany`, and decorators forward entries to the cache they wrap, so
+// every reader sees the plain item.
+
+// The `entryStore` interface is implemented by the providers and the decorators that can store an item
+// together with its entry metadata.
+type entryStore interface {
+	GetEntry(ctx context.Context, cacheKey string) (providers.Entry, bool, error)
+	SetEntry(ctx context.Context, cacheKey string, entry providers.Entry, ttl time.Duration) error
+}
+
+// The `getEntry` function retrieves an item with its entry metadata from a cache. Caches that don't
+// implement `entryStore` return the item as an entry without a soft expiry.
+func getEntry(ctx context.Context, cache CacheInterface, cacheKey string) (providers.Entry, bool, error) {
+	if store, ok := cache.(entryStore); ok {
+		return store.GetEntry(ctx, cacheKey)
+	}
+
+	item, found, err := cache.Get(ctx, cacheKey)
+
+	return providers.Entry{Value: item}, found, err
+}
+
+// The `setEntry` function stores an item with its entry metadata and the given hard TTL. Caches that
+// don't implement `entryStore` can't keep the metadata, so the item is stored with `Set` and the TTL
+// from their configuration instead, and is never served stale.
+func setEntry(ctx context.Context, cache CacheInterface, cacheKey string, entry providers.Entry, ttl time.Duration) error {
+	if store, ok := cache.(entryStore); ok {
+		return store.SetEntry(ctx, cacheKey, entry, ttl)
+	}
+
+	return cache.Set(ctx, cacheKey, entry.Value)
+}
+
+// The `entryStale` function reports whether the item is past its soft expiry, and by how long. Items
+// without a soft expiry are always fresh.
+func entryStale(entry providers.Entry, now time.Time) (time.Duration, bool) {
+	if entry.SoftExpiry.IsZero() || now.Before(entry.SoftExpiry) {
+		return 0, false
+	}
+
+	return now.Sub(entry.SoftExpiry), true
+}
+
+// The `expiresEarly` function decides whether a fresh item should be reloaded before its soft expiry,
+// using the XFetch algorithm: the item expires early if `now - loadDuration * beta * ln(rand)` is past
+// its soft expiry. The probability grows as the expiry approaches, and earlier for items that take
+// longer to load, so usually a single caller reloads a hot item before it expires for everyone.
+func expiresEarly(entry providers.Entry, now time.Time, beta float64) bool {
+	if beta <= 0 || entry.SoftExpiry.IsZero() || entry.LoadDuration <= 0 {
+		return false
+	}
+
+	// 1 - rand.Float64() is in (0, 1], so the logarithm is finite and not positive
+	gap := -float64(entry.LoadDuration) * beta * math.Log(1-rand.Float64())
+
+	return !now.Add(time.Duration(gap)).Before(entry.SoftExpiry)
+}
+
+// The `useEnvelope` function reports whether `GetOrLoad` stores loaded items with their entry metadata.
+func useEnvelope(cfg config.Config) bool {
+	return staleWindow(cfg) > 0 || cfg.EarlyExpirationBeta > 0
+}
+
+// The `staleWindow` function returns how long after the soft expiry items are kept in the cache, or
+// zero if stale serving is disabled in the configuration.
+func staleWindow(cfg config.Config) time.Duration {
+	return max(cfg.StaleWhileRevalidate, cfg.StaleIfError, 0)
+}
@@ -0,0 +1,148 @@
+package cachego
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"github.com/wasilak/cachego/providers"
+)
+
+// staleConfig returns the configuration of a memory cache serving stale items with the given TTL.
+func staleConfig(expiration string) config.Config {
+	return config.Config{
+		Expiration:          expiration,
+		StaleIfError:        time.Minute,
+		EarlyExpirationBeta: 1,
+	}
+}
+
+func TestGetOrLoadEntriesAreHiddenFromReaders(t *testing.T) {
+	ctx := context.Background()
+	memory := newTestCache(t, staleConfig("1m"))
+
+	encrypted, err := NewEncryptedCache(memory, EncryptionConfig{Keys: map[string][]byte{"a": testKeyA}, ActiveKeyID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tiered, err := NewTiered(ctx, memory, TieredConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caches := map[string]CacheInterface{
+		"memory":     memory,
+		"namespaced": NewNamespacedCache(memory, "ns"),
+		"encrypted":  encrypted,
+		"tiered":     tiered,
+	}
+
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			loaded, err := GetOrLoad(ctx, cache, name, func(context.Context) ([]byte, error) {
+				return []byte(`{"a":1}`), nil
+			})
+			if err != nil || string(loaded) != `{"a":1}` {
+				t.Fatalf("GetOrLoad() = %q, %v", loaded, err)
+			}
+
+			if item, found, err := cache.Get(ctx, name); err != nil || !found || string(item) != `{"a":1}` {
+				t.Errorf("Get() = %q, %v, %v, want the loaded item", item, found, err)
+			}
+
+			if items, err := cache.GetMany(ctx, []string{name}); err != nil || string(items[name]) != `{"a":1}` {
+				t.Errorf("GetMany() = %q, %v, want the loaded item", items, err)
+			}
+
+			value, found, err := NewTyped[map[string]int](cache, nil).Get(ctx, name)
+			if err != nil || !found || value["a"] != 1 {
+				t.Errorf("Typed.Get() = %v, %v, %v, want a=1", value, found, err)
+			}
+
+			ttl, found, err := cache.GetItemTTL(ctx, name)
+			if err != nil || !found || ttl > time.Minute {
+				t.Errorf("GetItemTTL() = %v, %v, %v, want at most the TTL without the stale window", ttl, found, err)
+			}
+
+			entry, found, err := getEntry(ctx, cache, name)
+			if err != nil || !found || entry.SoftExpiry.IsZero() {
+				t.Errorf("getEntry() = %+v, %v, %v, want an entry with a soft expiry", entry, found, err)
+			}
+		})
+	}
+}
+
+func TestGetOrLoadServesStaleOnError(t *testing.T) {
+	ctx := context.Background()
+	cache := newTestCache(t, staleConfig("20ms"))
+
+	loaded, err := GetOrLoad(ctx, cache, "k", func(context.Context) ([]byte, error) {
+		return []byte("v1"), nil
+	})
+	if err != nil || string(loaded) != "v1" {
+		t.Fatalf("GetOrLoad() = %q, %v", loaded, err)
+	}
+
+	time.Sleep(40 * time.Millisecond)
+
+	if ttl, found, _ := cache.GetItemTTL(ctx, "k"); !found || ttl != 0 {
+		t.Errorf("GetItemTTL() of a stale item = %v, %v, want 0, true", ttl, found)
+	}
+
+	failing := errors.New("source down")
+
+	item, err := GetOrLoad(ctx, cache, "k", func(context.Context) ([]byte, error) {
+		return nil, failing
+	})
+	if err != nil || string(item) != "v1" {
+		t.Errorf("GetOrLoad() with a failing loader = %q, %v, want the stale item", item, err)
+	}
+
+	if _, err := GetOrLoad(ctx, cache, "missing", func(context.Context) ([]byte, error) {
+		return nil, failing
+	}); !errors.Is(err, failing) {
+		t.Errorf("GetOrLoad() of a missing item with a failing loader = %v, want the loader error", err)
+	}
+}
+
+func TestEntryStale(t *testing.T) {
+	now := time.Now()
+
+	if _, stale := entryStale(providers.Entry{}, now); stale {
+		t.Error("an entry without a soft expiry is stale")
+	}
+
+	if _, stale := entryStale(providers.Entry{SoftExpiry: now.Add(time.Second)}, now); stale {
+		t.Error("an entry before its soft expiry is stale")
+	}
+
+	if age, stale := entryStale(providers.Entry{SoftExpiry: now.Add(-time.Second)}, now); !stale || age != time.Second {
+		t.Errorf("entryStale() = %v, %v, want 1s, true", age, stale)
+	}
+}
+
+func TestExpiresEarly(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name  string
+		entry providers.Entry
+		beta  float64
+		want  bool
+	}{
+		{"disabled", providers.Entry{SoftExpiry: now, LoadDuration: time.Hour}, 0, false},
+		{"no soft expiry", providers.Entry{LoadDuration: time.Hour}, 1, false},
+		{"unknown load duration", providers.Entry{SoftExpiry: now.Add(time.Nanosecond)}, 1, false},
+		{"at the soft expiry", providers.Entry{SoftExpiry: now, LoadDuration: time.Millisecond}, 1, true},
+		{"far from the soft expiry", providers.Entry{SoftExpiry: now.Add(24 * time.Hour), LoadDuration: time.Nanosecond}, 1, false},
+	}
+
+	for _, tt := range tests {
+		if got := expiresEarly(tt.entry, now, tt.beta); got != tt.want {
+			t.Errorf("%s: expiresEarly() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
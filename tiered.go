@@ -0,0 +1,421 @@
+package cachego
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+	"github.com/wasilak/cachego/providers"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// `defaultL1TTL` is the TTL of items in the L1 tier when `TieredConfig.L1TTL` is not set.
+const defaultL1TTL = time.Minute
+
+// `l1EvictionRatio` is the share of `L1MaxItems` evicted at once when the L1 tier is full, so the
+// eviction cost is amortized over many writes.
+const l1EvictionRatio = 0.1
+
+// The `TieredConfig` type represents the configuration of a `TieredCache`.
+// @property L1TTL - The `L1TTL` property is the TTL of items in the in-memory L1 tier. It bounds how
+// long an item changed or deleted in L2 by another process can still be served from L1. It defaults to
+// one minute.
+// @property {int} L1MaxItems - The `L1MaxItems` property is the maximum number of items kept in the
+// L1 tier. When it is reached, the items closest to expiry are evicted. Zero means no limit.
+// @property Bus - The `Bus` property is an optional `InvalidationBus` shared by all instances of the
+// tiered cache. Writes and deletes are published on it and evict the key from the L1 tier of the other
+// instances, so they don't serve a stale copy until `L1TTL` passes.
+type TieredConfig struct {
+	L1TTL      time.Duration
+	L1MaxItems int
+	Bus        InvalidationBus
+}
+
+// The `TieredCache` type composes an in-memory `GoCache` as L1 in front of any other `CacheInterface`
+// as L2, e.g. Redis or Badger, so hot keys are served without a network round-trip. Reads check L1
+// first and `Get` promotes L2 hits to L1, writes go through to both tiers, and TTL operations report and
+// change the authoritative expiry in L2. Items are kept in L1 for at most `L1TTL`, and never longer
+// than their TTL in L2 when it is known at write time.
+type TieredCache struct {
+	CacheInterface
+	l1          *providers.GoCache
+	l1TTL       time.Duration
+	maxItems    int
+	tracer      trace.Tracer
+	evictLock   sync.Mutex
+	invalidator *invalidator
+}
+
+// The `NewTiered` function creates a `TieredCache` with a new L1 tier in front of the given L2 cache.
+// The L2 cache must already be initialized. Closing the tiered cache closes both tiers.
+func NewTiered(ctx context.Context, l2 CacheInterface, cfg TieredConfig) (*TieredCache, error) {
+	if cfg.L1TTL <= 0 {
+		cfg.L1TTL = defaultL1TTL
+	}
+
+	l2Config := l2.GetConfig()
+
+	l1, err := New(ctx, config.Config{
+		Type:       "memory",
+		Expiration: cfg.L1TTL.String(),
+		Meter:      l2Config.Meter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c := &TieredCache{
+		CacheInterface: l2,
+		l1:             l1.(*providers.GoCache),
+		l1TTL:          cfg.L1TTL,
+		maxItems:       cfg.L1MaxItems,
+		tracer:         otel.Tracer("TieredCache"),
+	}
+
+	if cfg.Bus != nil {
+		c.invalidator, err = newInvalidator(cfg.Bus, c.l1)
+		if err != nil {
+			c.l1.Close(ctx)
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// The `Get` function retrieves an item from L1, or from L2 on a miss, in which case the item is
+// promoted to L1 for at most its remaining TTL in L2. The tier that served the item is recorded in the
+// `cache.tier` span attribute.
+func (c *TieredCache) Get(ctx context.Context, cacheKey string) ([]byte, bool, error) {
+	ctx, span := c.tracer.Start(ctx, "Get")
+	defer span.End()
+
+	entry, found, err := c.getEntry(ctx, span, cacheKey)
+
+	return entry.Value, found, err
+}
+
+// The `GetEntry` function retrieves an item with the metadata stored by `GetOrLoad` from L1, or from L2
+// on a miss, in which case the item is promoted to L1 together with its metadata.
+func (c *TieredCache) GetEntry(ctx context.Context, cacheKey string) (providers.Entry, bool, error) {
+	ctx, span := c.tracer.Start(ctx, "GetEntry")
+	defer span.End()
+
+	return c.getEntry(ctx, span, cacheKey)
+}
+
+// The `getEntry` function reads an item from L1 or L2 and promotes L2 hits to L1 for at most their
+// remaining TTL in L2. The tier that served the item is recorded on the span.
+func (c *TieredCache) getEntry(ctx context.Context, span trace.Span, cacheKey string) (providers.Entry, bool, error) {
+	entry, found, err := c.l1.GetEntry(ctx, cacheKey)
+	if err == nil && found {
+		span.SetAttributes(attribute.String("cache.tier", "l1"))
+		return entry, true, nil
+	}
+
+	entry, found, err = getEntry(ctx, c.CacheInterface, cacheKey)
+	if err != nil || !found {
+		return entry, found, err
+	}
+
+	span.SetAttributes(attribute.String("cache.tier", "l2"))
+
+	if ttl, ok := c.promotionTTL(ctx, cacheKey); ok {
+		c.setL1(ctx, cacheKey, entry, ttl)
+	}
+
+	return entry, true, nil
+}
+
+// The `Set` function stores an item in L2 and then in L1, with the TTL from the L2 configuration.
+func (c *TieredCache) Set(ctx context.Context, cacheKey string, item []byte) error {
+	return c.SetWithTTL(ctx, cacheKey, item, DefaultExpiration)
+}
+
+// The `SetWithTTL` function stores an item in L2 with its own TTL and then in L1, where it expires
+// after `L1TTL` or its own TTL, whichever comes first. If the write to L2 fails, the item is removed
+// from L1, so L1 never serves a value L2 doesn't have.
+func (c *TieredCache) SetWithTTL(ctx context.Context, cacheKey string, item []byte, ttl time.Duration) error {
+	ctx, span := c.tracer.Start(ctx, "SetWithTTL")
+	defer span.End()
+
+	if err := c.CacheInterface.SetWithTTL(ctx, cacheKey, item, ttl); err != nil {
+		c.l1.Delete(ctx, cacheKey)
+		return err
+	}
+
+	c.setL1(ctx, cacheKey, providers.Entry{Value: item}, c.l1TTLFor(ttl))
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: []string{cacheKey}})
+
+	return nil
+}
+
+// The `SetEntry` function stores an item with the metadata used by `GetOrLoad` in L2 and then in L1,
+// where it expires after `L1TTL` or its hard TTL, whichever comes first.
+func (c *TieredCache) SetEntry(ctx context.Context, cacheKey string, entry providers.Entry, ttl time.Duration) error {
+	ctx, span := c.tracer.Start(ctx, "SetEntry")
+	defer span.End()
+
+	if err := setEntry(ctx, c.CacheInterface, cacheKey, entry, ttl); err != nil {
+		c.l1.Delete(ctx, cacheKey)
+		return err
+	}
+
+	c.setL1(ctx, cacheKey, entry, c.l1TTLFor(ttl))
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: []string{cacheKey}})
+
+	return nil
+}
+
+// The `Touch` function resets the TTL of an item in L2. The item is only touched in L1 if the new TTL
+// is shorter than `L1TTL`, so L1 doesn't outlive L2.
+func (c *TieredCache) Touch(ctx context.Context, cacheKey string, ttl time.Duration) error {
+	if err := c.CacheInterface.Touch(ctx, cacheKey, ttl); err != nil {
+		return err
+	}
+
+	if l1TTL := c.l1TTLFor(ttl); l1TTL < c.l1TTL {
+		return c.l1.Touch(ctx, cacheKey, l1TTL)
+	}
+
+	return nil
+}
+
+// The `ExtendTTL` function extends the TTL of an item in L2. Shortening the TTL removes the item from
+// L1, as its remaining TTL in L2 is not known.
+func (c *TieredCache) ExtendTTL(ctx context.Context, cacheKey string, by time.Duration) error {
+	if err := c.CacheInterface.ExtendTTL(ctx, cacheKey, by); err != nil {
+		return err
+	}
+
+	if by < 0 && by != NoExpiration {
+		return c.l1.Delete(ctx, cacheKey)
+	}
+
+	return nil
+}
+
+// The `Delete` function removes an item from both tiers.
+func (c *TieredCache) Delete(ctx context.Context, cacheKey string) error {
+	c.l1.Delete(ctx, cacheKey)
+
+	if err := c.CacheInterface.Delete(ctx, cacheKey); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: []string{cacheKey}})
+
+	return nil
+}
+
+// The `DeleteMany` function removes multiple items from both tiers.
+func (c *TieredCache) DeleteMany(ctx context.Context, cacheKeys []string) error {
+	c.l1.DeleteMany(ctx, cacheKeys)
+
+	if err := c.CacheInterface.DeleteMany(ctx, cacheKeys); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: cacheKeys})
+
+	return nil
+}
+
+// The `Has` function checks L1 first and L2 on a miss.
+func (c *TieredCache) Has(ctx context.Context, cacheKey string) (bool, error) {
+	if found, _ := c.l1.Has(ctx, cacheKey); found {
+		return true, nil
+	}
+
+	return c.CacheInterface.Has(ctx, cacheKey)
+}
+
+// The `GetMany` function retrieves the items found in L1 and fetches the rest from L2 in a single
+// call. Items found in L2 are not promoted to L1: their remaining TTL in L2 is unknown and looking it up
+// would cost a round-trip per key, while promoting them with a longer TTL would keep them in L1 after
+// they expired in L2. `Get` promotes them instead.
+func (c *TieredCache) GetMany(ctx context.Context, cacheKeys []string) (map[string][]byte, error) {
+	ctx, span := c.tracer.Start(ctx, "GetMany")
+	defer span.End()
+
+	items, err := c.l1.GetMany(ctx, cacheKeys)
+	if err != nil {
+		items = make(map[string][]byte, len(cacheKeys))
+	}
+
+	missing := make([]string, 0, len(cacheKeys)-len(items))
+	for _, cacheKey := range cacheKeys {
+		if _, ok := items[cacheKey]; !ok {
+			missing = append(missing, cacheKey)
+		}
+	}
+
+	span.SetAttributes(
+		attribute.Int("cache.tier.l1_hits", len(items)),
+		attribute.Int("cache.tier.l2_lookups", len(missing)),
+	)
+
+	if len(missing) == 0 {
+		return items, nil
+	}
+
+	found, err := c.CacheInterface.GetMany(ctx, missing)
+	if err != nil {
+		return nil, err
+	}
+
+	for cacheKey, item := range found {
+		items[cacheKey] = item
+	}
+
+	return items, nil
+}
+
+// The `SetMany` function stores multiple items in L2 and then in L1.
+func (c *TieredCache) SetMany(ctx context.Context, items map[string][]byte) error {
+	if err := c.CacheInterface.SetMany(ctx, items); err != nil {
+		c.l1.DeleteMany(ctx, mapKeys(items))
+		return err
+	}
+
+	ttl := c.l1TTLFor(DefaultExpiration)
+	for cacheKey, item := range items {
+		c.setL1(ctx, cacheKey, providers.Entry{Value: item}, ttl)
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Keys: mapKeys(items)})
+
+	return nil
+}
+
+// The `Clear` function removes all items from both tiers.
+func (c *TieredCache) Clear(ctx context.Context) error {
+	c.l1.Clear(ctx)
+
+	if err := c.CacheInterface.Clear(ctx); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{All: true})
+
+	return nil
+}
+
+// The `ClearPrefix` function removes all items whose cache keys start with the given prefix from both
+// tiers. It returns `ErrClearPrefixUnsupported` if L2 does not implement `PrefixClearer`.
+func (c *TieredCache) ClearPrefix(ctx context.Context, prefix string) error {
+	clearer, ok := c.CacheInterface.(PrefixClearer)
+	if !ok {
+		return ErrClearPrefixUnsupported
+	}
+
+	c.l1.ClearPrefix(ctx, prefix)
+
+	if err := clearer.ClearPrefix(ctx, prefix); err != nil {
+		return err
+	}
+
+	c.invalidator.publish(ctx, InvalidationEvent{Prefix: prefix, All: prefix == ""})
+
+	return nil
+}
+
+// The `Close` function cancels the subscription to the invalidation bus and closes both tiers.
+func (c *TieredCache) Close(ctx context.Context) error {
+	c.invalidator.close()
+	c.l1.Close(ctx)
+
+	return c.CacheInterface.Close(ctx)
+}
+
+// The `Lock` function acquires the distributed lock of L2 used by `GetOrLoad`. If L2 has no
+// distributed lock, the lock is always acquired locally.
+func (c *TieredCache) Lock(ctx context.Context, cacheKey string, ttl time.Duration) (func(context.Context) error, bool, error) {
+	return lockThrough(ctx, c.CacheInterface, cacheKey, ttl)
+}
+
+// The `l1TTLFor` function returns the L1 TTL of an item written to L2 with the given TTL: `L1TTL`,
+// capped to the item's own TTL.
+func (c *TieredCache) l1TTLFor(ttl time.Duration) time.Duration {
+	if ttl == DefaultExpiration {
+		ttl = c.CacheInterface.GetConfig().TTL
+	}
+
+	if ttl == NoExpiration || ttl <= 0 || ttl > c.l1TTL {
+		return c.l1TTL
+	}
+
+	return ttl
+}
+
+// The `promotionTTL` function returns the L1 TTL of an item promoted from L2: `L1TTL`, capped to the
+// remaining TTL of the item in L2. It returns false if the item should not be promoted, because it is
+// about to expire or its TTL can't be read.
+func (c *TieredCache) promotionTTL(ctx context.Context, cacheKey string) (time.Duration, bool) {
+	ttl, found, err := c.CacheInterface.GetItemTTL(ctx, cacheKey)
+	if err != nil || !found {
+		return 0, false
+	}
+
+	if ttl == NoExpiration || ttl > c.l1TTL {
+		return c.l1TTL, true
+	}
+
+	return ttl, ttl > 0
+}
+
+// The `setL1` function stores an item in L1, making room for it first if `L1MaxItems` is reached.
+func (c *TieredCache) setL1(ctx context.Context, cacheKey string, entry providers.Entry, ttl time.Duration) {
+	if c.maxItems > 0 && c.l1.Cache.ItemCount() >= c.maxItems {
+		c.evictL1()
+	}
+
+	c.l1.SetEntry(ctx, cacheKey, entry, ttl)
+}
+
+// The `evictL1` function removes the expired items from L1 and, if it is still full, the items closest
+// to expiry until `l1EvictionRatio` of `L1MaxItems` is free.
+func (c *TieredCache) evictL1() {
+	c.evictLock.Lock()
+	defer c.evictLock.Unlock()
+
+	c.l1.Cache.DeleteExpired()
+
+	target := c.maxItems - int(float64(c.maxItems)*l1EvictionRatio) - 1
+	if c.l1.Cache.ItemCount() <= target {
+		return
+	}
+
+	type entry struct {
+		key        string
+		expiration int64
+	}
+
+	items := c.l1.Cache.Items()
+	entries := make([]entry, 0, len(items))
+	for key, item := range items {
+		entries = append(entries, entry{key, item.Expiration})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].expiration < entries[j].expiration
+	})
+
+	for _, e := range entries[:max(len(entries)-max(target, 0), 0)] {
+		c.l1.Cache.Delete(e.key)
+	}
+}
+
+// The `mapKeys` function returns the keys of an items map.
+func mapKeys(items map[string][]byte) []string {
+	keys := make([]string, 0, len(items))
+	for cacheKey := range items {
+		keys = append(keys, cacheKey)
+	}
+
+	return keys
+}
@@ -0,0 +1,206 @@
+package cachego
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/wasilak/cachego/config"
+)
+
+func newTestTieredCache(t *testing.T, l2 CacheInterface, cfg TieredConfig) *TieredCache {
+	t.Helper()
+
+	tiered, err := NewTiered(context.Background(), l2, cfg)
+	if err != nil {
+		t.Fatalf("NewTiered() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		tiered.invalidator.close()
+		tiered.l1.Close(context.Background())
+	})
+
+	return tiered
+}
+
+func TestTieredCacheWrites(t *testing.T) {
+	ctx := context.Background()
+	l2 := newTestCache(t, config.Config{})
+	c := newTestTieredCache(t, l2, TieredConfig{L1TTL: time.Minute})
+
+	tests := []struct {
+		name      string
+		write     func() error
+		key       string
+		want      string
+		wantL1TTL time.Duration
+	}{
+		{
+			name:      "Set",
+			write:     func() error { return c.Set(ctx, "set", []byte("v")) },
+			key:       "set",
+			want:      "v",
+			wantL1TTL: time.Minute,
+		},
+		{
+			name:      "SetWithTTL shorter than L1TTL",
+			write:     func() error { return c.SetWithTTL(ctx, "short", []byte("v"), 10*time.Second) },
+			key:       "short",
+			want:      "v",
+			wantL1TTL: 10 * time.Second,
+		},
+		{
+			name:      "SetWithTTL without expiry",
+			write:     func() error { return c.SetWithTTL(ctx, "forever", []byte("v"), NoExpiration) },
+			key:       "forever",
+			want:      "v",
+			wantL1TTL: time.Minute,
+		},
+		{
+			name:      "SetMany",
+			write:     func() error { return c.SetMany(ctx, map[string][]byte{"many": []byte("v")}) },
+			key:       "many",
+			want:      "v",
+			wantL1TTL: time.Minute,
+		},
+		{
+			name:  "Delete",
+			write: func() error { return c.Delete(ctx, "set") },
+			key:   "set",
+		},
+		{
+			name:  "DeleteMany",
+			write: func() error { return c.DeleteMany(ctx, []string{"short", "forever"}) },
+			key:   "forever",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.write(); err != nil {
+				t.Fatal(err)
+			}
+
+			for tier, cache := range map[string]CacheInterface{"L1": c.l1, "L2": l2} {
+				item, found, err := cache.Get(ctx, tt.key)
+				if err != nil || found != (tt.want != "") || string(item) != tt.want {
+					t.Errorf("%s Get(%q) = %q, %v, %v, want %q", tier, tt.key, item, found, err, tt.want)
+				}
+			}
+
+			if tt.wantL1TTL == 0 {
+				return
+			}
+
+			if ttl, _, _ := c.l1.GetItemTTL(ctx, tt.key); ttl > tt.wantL1TTL || ttl < tt.wantL1TTL-time.Second {
+				t.Errorf("L1 GetItemTTL(%q) = %v, want about %v", tt.key, ttl, tt.wantL1TTL)
+			}
+		})
+	}
+}
+
+func TestTieredCachePromotion(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		read         func(c *TieredCache) (string, bool)
+		wantPromoted bool
+	}{
+		{
+			name: "Get",
+			read: func(c *TieredCache) (string, bool) {
+				item, found, _ := c.Get(ctx, "k")
+				return string(item), found
+			},
+			wantPromoted: true,
+		},
+		{
+			name: "GetMany",
+			read: func(c *TieredCache) (string, bool) {
+				items, _ := c.GetMany(ctx, []string{"k"})
+				item, found := items["k"]
+				return string(item), found
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l2 := newTestCache(t, config.Config{})
+			c := newTestTieredCache(t, l2, TieredConfig{L1TTL: time.Minute})
+
+			if err := l2.SetWithTTL(ctx, "k", []byte("v"), 100*time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+
+			if item, found := tt.read(c); !found || item != "v" {
+				t.Fatalf("read from L2 = %q, %v, want %q, true", item, found, "v")
+			}
+
+			ttl, promoted, _ := c.l1.GetItemTTL(ctx, "k")
+			if promoted != tt.wantPromoted || ttl > 100*time.Millisecond {
+				t.Errorf("L1 GetItemTTL() = %v, %v, want promoted %v for at most the TTL in L2", ttl, promoted, tt.wantPromoted)
+			}
+
+			time.Sleep(150 * time.Millisecond)
+
+			if item, found := tt.read(c); found {
+				t.Errorf("read after the item expired in L2 = %q, true, want it to be missing", item)
+			}
+		})
+	}
+}
+
+func TestTieredCacheL1MaxItems(t *testing.T) {
+	ctx := context.Background()
+	c := newTestTieredCache(t, newTestCache(t, config.Config{}), TieredConfig{L1MaxItems: 10})
+
+	for i := range 50 {
+		if err := c.SetWithTTL(ctx, string(rune('a'+i)), []byte("v"), time.Duration(i+1)*time.Second); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := c.l1.Cache.ItemCount(); n > 10 {
+		t.Errorf("L1 items = %d, want at most 10", n)
+	}
+
+	if found, _ := c.l1.Has(ctx, string(rune('a'+49))); !found {
+		t.Error("the item furthest from expiry was evicted from L1")
+	}
+
+	if found, _ := c.Has(ctx, "a"); !found {
+		t.Error("an item evicted from L1 is missing from L2")
+	}
+}
+
+func TestTieredCacheBusEvictsOtherInstances(t *testing.T) {
+	ctx := context.Background()
+	bus := NewLocalBus()
+	l2 := newTestCache(t, config.Config{})
+
+	writer := newTestTieredCache(t, l2, TieredConfig{Bus: bus})
+	reader := newTestTieredCache(t, l2, TieredConfig{Bus: bus})
+
+	if err := writer.Set(ctx, "k", []byte("v1")); err != nil {
+		t.Fatal(err)
+	}
+
+	if item, _, _ := reader.Get(ctx, "k"); string(item) != "v1" {
+		t.Fatalf("Get() = %q, want %q", item, "v1")
+	}
+
+	if err := writer.Set(ctx, "k", []byte("v2")); err != nil {
+		t.Fatal(err)
+	}
+
+	if item, _, _ := reader.Get(ctx, "k"); string(item) != "v2" {
+		t.Errorf("Get() on the other instance = %q, want %q", item, "v2")
+	}
+
+	if item, _, _ := writer.Get(ctx, "k"); string(item) != "v2" {
+		t.Errorf("Get() on the writing instance = %q, want %q", item, "v2")
+	}
+}
@@ -0,0 +1,90 @@
+package cachego
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// The `DecodeError` type is returned by `Typed` when a cached item cannot be decoded with its codec,
+// e.g. because it was written with a different codec or an incompatible version of the type.
+// @property {string} Key - The `Key` property is the cache key of the item that failed to decode.
+// @property {error} Err - The `Err` property is the error returned by the codec.
+type DecodeError struct {
+	Key string
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("cachego: failed to decode cached item %q: %v", e.Key, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// The `Typed` type is a generic wrapper over any `CacheInterface` that stores values of type `T`
+// instead of raw bytes. Values are converted with the configured `Codec`, so call sites don't have to
+// marshal them by hand.
+// @property cache - The `cache` property is the underlying cache storing the encoded values.
+// @property codec - The `codec` property is used to encode and decode the values.
+type Typed[T any] struct {
+	cache CacheInterface
+	codec Codec
+}
+
+// The `NewTyped` function creates a `Typed` wrapper around the given cache. If codec is nil, values are
+// encoded as JSON.
+func NewTyped[T any](cache CacheInterface, codec Codec) *Typed[T] {
+	if codec == nil {
+		codec = JSONCodec{}
+	}
+
+	return &Typed[T]{
+		cache: cache,
+		codec: codec,
+	}
+}
+
+// The `Cache` function returns the underlying cache.
+func (t *Typed[T]) Cache() CacheInterface {
+	return t.cache
+}
+
+// The `Get` function retrieves an item from the cache and decodes it. It returns the value, a boolean
+// indicating if the item was found and an error. A value that cannot be decoded results in a
+// `*DecodeError`.
+func (t *Typed[T]) Get(ctx context.Context, cacheKey string) (T, bool, error) {
+	var value T
+
+	item, found, err := t.cache.Get(ctx, cacheKey)
+	if err != nil || !found {
+		return value, false, err
+	}
+
+	if err := t.codec.Unmarshal(item, &value); err != nil {
+		return value, false, &DecodeError{Key: cacheKey, Err: err}
+	}
+
+	return value, true, nil
+}
+
+// The `Set` function encodes the value and stores it in the cache with the configured TTL.
+func (t *Typed[T]) Set(ctx context.Context, cacheKey string, value T) error {
+	return t.SetWithTTL(ctx, cacheKey, value, DefaultExpiration)
+}
+
+// The `SetWithTTL` function encodes the value and stores it in the cache with its own TTL.
+func (t *Typed[T]) SetWithTTL(ctx context.Context, cacheKey string, value T, ttl time.Duration) error {
+	item, err := t.codec.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cachego: failed to encode item %q: %w", cacheKey, err)
+	}
+
+	return t.cache.SetWithTTL(ctx, cacheKey, item, ttl)
+}
+
+// The `Delete` function removes the item from the cache.
+func (t *Typed[T]) Delete(ctx context.Context, cacheKey string) error {
+	return t.cache.Delete(ctx, cacheKey)
+}
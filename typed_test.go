@@ -0,0 +1,107 @@
+package cachego
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/wasilak/cachego/config"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type testUser struct {
+	Name string
+	Age  int
+}
+
+func TestTypedRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	want := testUser{Name: "alice", Age: 42}
+
+	tests := []struct {
+		name  string
+		codec Codec
+	}{
+		{name: "default", codec: nil},
+		{name: "JSON", codec: JSONCodec{}},
+		{name: "gob", codec: GobCodec{}},
+		{name: "msgpack", codec: MsgpackCodec{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			typed := NewTyped[testUser](newTestCache(t, config.Config{}), tt.codec)
+
+			if err := typed.Set(ctx, "user", want); err != nil {
+				t.Fatal(err)
+			}
+
+			got, found, err := typed.Get(ctx, "user")
+			if err != nil || !found || got != want {
+				t.Errorf("Get() = %+v, %v, %v, want %+v, true, nil", got, found, err, want)
+			}
+
+			if err := typed.Delete(ctx, "user"); err != nil {
+				t.Fatal(err)
+			}
+
+			if got, found, err := typed.Get(ctx, "user"); err != nil || found || got != (testUser{}) {
+				t.Errorf("Get() after Delete() = %+v, %v, %v, want the zero value, false, nil", got, found, err)
+			}
+		})
+	}
+}
+
+func TestTypedProtoRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	typed := NewTyped[*wrapperspb.StringValue](newTestCache(t, config.Config{}), ProtoCodec{})
+
+	if err := typed.Set(ctx, "k", wrapperspb.String("v")); err != nil {
+		t.Fatal(err)
+	}
+
+	got, found, err := typed.Get(ctx, "k")
+	if err != nil || !found || !proto.Equal(got, wrapperspb.String("v")) {
+		t.Errorf("Get() = %v, %v, %v, want %q, true, nil", got, found, err, "v")
+	}
+
+	if err := NewTyped[testUser](typed.Cache(), ProtoCodec{}).Set(ctx, "k", testUser{}); err == nil {
+		t.Error("Set() of a value that is not a proto.Message error = nil")
+	}
+}
+
+func TestTypedDecodeError(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		item  []byte
+		codec Codec
+	}{
+		{name: "JSON", item: []byte("not json"), codec: JSONCodec{}},
+		{name: "gob", item: []byte("not gob"), codec: GobCodec{}},
+		{name: "msgpack", item: []byte{0xc1}, codec: MsgpackCodec{}},
+		{name: "written with another codec", item: []byte(`{"Name":"alice","Age":42}`), codec: GobCodec{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newTestCache(t, config.Config{})
+			if err := cache.Set(ctx, "user", tt.item); err != nil {
+				t.Fatal(err)
+			}
+
+			got, found, err := NewTyped[testUser](cache, tt.codec).Get(ctx, "user")
+
+			var decodeErr *DecodeError
+			if !errors.As(err, &decodeErr) || decodeErr.Key != "user" || decodeErr.Err == nil {
+				t.Fatalf("Get() error = %v, want a *DecodeError for %q", err, "user")
+			}
+
+			if found || got != (testUser{}) {
+				t.Errorf("Get() = %+v, %v, want the zero value, false", got, found)
+			}
+		})
+	}
+}